@@ -4,36 +4,124 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"time"
 
+	"hot-coffee/internal/config"
 	"hot-coffee/internal/dal"
 	"hot-coffee/internal/handler"
 	"hot-coffee/internal/service"
 )
 
-// StartServer initializes and starts the HTTP server on the specified port
-func StartServer(port string) error {
-	// Ensure the data directory exists
-	dal.NewDirectory(*dir)
+// StartServer initializes and starts the HTTP server with the given configuration
+func StartServer(cfg config.Config) error {
+	// Keep commands that change data, such as restore, off the data directory while the server runs
+	unlock, err := dal.LockDataDir(cfg)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	// Recover, create or validate the data files; never start on corrupt data
+	if err := dal.Bootstrap(cfg); err != nil {
+		return err
+	}
+	// Load the data files into memory once; repositories serve reads from this store
+	store, err := dal.LoadStore(cfg)
+	if err != nil {
+		return err
+	}
+	// Locks shared by every repository so that requests touching the same file are serialized
+	locks := dal.NewFileLocks()
 	// Set up Aggregations: repository, service, and handler
-	aggregationsRepo := dal.NewAggregationsRepository()
+	aggregationsRepo := dal.NewAggregationsRepository(store, locks)
 	aggregationsService := service.NewAggregationsService(aggregationsRepo)
 	aggregationsHandler := handler.NewAggregationsHandler(aggregationsService)
 	http.HandleFunc("GET /reports/total-sales", aggregationsHandler.TotalSales)
 	http.HandleFunc("GET /reports/popular-items", aggregationsHandler.PopularItems)
+	http.HandleFunc("GET /reports/tax", aggregationsHandler.TaxReport)
 
 	// Set up Orders: repository, service, and handler
-	orderRepo := dal.NewJSONOrderRepository()
-	orderService := service.NewOrderService(orderRepo)
+	orderRepo := dal.NewJSONOrderRepository(store, locks)
+	idGen, err := service.NewIDGenerator(cfg.IDStrategy, dal.NewJSONSequenceRepository(cfg), orderRepo)
+	if err != nil {
+		return err
+	}
+	// Every saved change to an order is published on the bus, which feeds the live queue stream
+	orderEvents := service.NewEventBus()
+	orderService := service.NewOrderService(orderRepo, idGen, cfg.MaxOpenOrdersPerCustomer, time.Duration(cfg.PickupLeadTime), orderEvents)
+	if err := orderService.CapturePrices(); err != nil {
+		return err
+	}
+	// Move pre-orders that became due while the server was down into the queue, then keep checking
+	if err := orderService.ActivateScheduledOrders(); err != nil {
+		return err
+	}
+	orderService.StartPickupScheduler(service.PickupCheckInterval)
 	orderHandler := handler.NewOrderHandler(orderService)
-	http.HandleFunc("POST /orders", orderHandler.PostOrders)
+	// Retried creates, payments and closes carrying the same Idempotency-Key get the first response
+	idempotencyService, err := service.NewIdempotencyService(dal.NewJSONIdempotencyRepository(cfg), time.Duration(cfg.IdempotencyTTL))
+	if err != nil {
+		return err
+	}
+	http.HandleFunc("POST /orders", handler.Idempotent(idempotencyService, orderHandler.PostOrders))
 	http.HandleFunc("GET /orders", orderHandler.GetOrders)
+	http.HandleFunc("POST /orders/quote", orderHandler.PostOrdersQuote)
 	http.HandleFunc("GET /orders/{id}", orderHandler.GetOrdersID)
 	http.HandleFunc("PUT /orders/{id}", orderHandler.PutOrdersID)
 	http.HandleFunc("DELETE /orders/{id}", orderHandler.DeleteOrdersID)
-	http.HandleFunc("POST /orders/{id}/close", orderHandler.PostOrdersIDClose)
+	http.HandleFunc("POST /orders/{id}/close", handler.Idempotent(idempotencyService, orderHandler.PostOrdersIDClose))
+	http.HandleFunc("POST /orders/{id}/payments", handler.Idempotent(idempotencyService, orderHandler.PostOrdersIDPayments))
+	http.HandleFunc("GET /orders/{id}/payments", orderHandler.GetOrdersIDPayments)
+	http.HandleFunc("POST /orders/{id}/transition", orderHandler.PostOrdersIDTransition)
+	http.HandleFunc("POST /orders/{id}/cancel", orderHandler.PostOrdersIDCancel)
+	http.HandleFunc("POST /orders/{id}/refund", orderHandler.PostOrdersIDRefund)
+	queueHandler := handler.NewQueueHandler(orderService)
+	http.HandleFunc("GET /queue", queueHandler.GetQueue)
+	http.HandleFunc("GET /queue/stream", queueHandler.StreamQueue)
+
+	// Set up Customers: repository, service, and handler
+	customerRepo := dal.NewJSONCustomerRepository(store, locks)
+	customerService := service.NewCustomerService(customerRepo)
+	customerHandler := handler.NewCustomerHandler(customerService, orderService)
+	http.HandleFunc("POST /customers", customerHandler.PostCustomers)
+	http.HandleFunc("GET /customers", customerHandler.GetCustomers)
+	http.HandleFunc("GET /customers/{id}", customerHandler.GetCustomersID)
+	http.HandleFunc("PUT /customers/{id}", customerHandler.PutCustomersID)
+	http.HandleFunc("DELETE /customers/{id}", customerHandler.DeleteCustomersID)
+	http.HandleFunc("GET /customers/{id}/orders", customerHandler.GetCustomersIDOrders)
+
+	// Set up Loyalty: repository, service, and handler
+	loyaltyRepo := dal.NewJSONLoyaltyRepository(store, locks)
+	loyaltyService := service.NewLoyaltyService(loyaltyRepo)
+	loyaltyHandler := handler.NewLoyaltyHandler(loyaltyService)
+	http.HandleFunc("GET /loyalty/rules", loyaltyHandler.GetRules)
+	http.HandleFunc("POST /loyalty/rules", loyaltyHandler.PostRules)
+	http.HandleFunc("PUT /loyalty/rules/{id}", loyaltyHandler.PutRulesID)
+	http.HandleFunc("DELETE /loyalty/rules/{id}", loyaltyHandler.DeleteRulesID)
+	http.HandleFunc("GET /customers/{id}/loyalty", loyaltyHandler.GetCustomerBalances)
+	http.HandleFunc("GET /customers/{id}/loyalty/ledger", loyaltyHandler.GetCustomerLedger)
+	http.HandleFunc("POST /customers/{id}/loyalty/adjustments", loyaltyHandler.PostCustomerAdjustment)
+
+	// Set up Promotions: repository, service, and handler
+	promotionRepo := dal.NewJSONPromotionRepository(store, locks)
+	promotionService := service.NewPromotionService(promotionRepo)
+	promotionHandler := handler.NewPromotionHandler(promotionService)
+	http.HandleFunc("GET /promotions", promotionHandler.GetPromotions)
+	http.HandleFunc("POST /promotions", promotionHandler.PostPromotions)
+	http.HandleFunc("GET /promotions/{id}", promotionHandler.GetPromotionsID)
+	http.HandleFunc("PUT /promotions/{id}", promotionHandler.PutPromotionsID)
+	http.HandleFunc("DELETE /promotions/{id}", promotionHandler.DeletePromotionsID)
+
+	// Set up Taxes: repository, service, and handler
+	taxRepo := dal.NewJSONTaxRepository(store, locks)
+	taxService := service.NewTaxService(taxRepo)
+	taxHandler := handler.NewTaxHandler(taxService)
+	http.HandleFunc("GET /tax/rates", taxHandler.GetRates)
+	http.HandleFunc("POST /tax/rates", taxHandler.PostRates)
+	http.HandleFunc("PUT /tax/rates/{id}", taxHandler.PutRatesID)
+	http.HandleFunc("DELETE /tax/rates/{id}", taxHandler.DeleteRatesID)
 
 	// Set up Menu: repository, service, and handler
-	menuRepo := dal.NewJSONMenuRepository()
+	menuRepo := dal.NewJSONMenuRepository(store, locks)
 	menuService := service.NewMenuService(menuRepo)
 	menuHandler := handler.NewMenuHandler(menuService)
 	http.HandleFunc("POST /menu", menuHandler.PostMenu)
@@ -43,7 +131,7 @@ func StartServer(port string) error {
 	http.HandleFunc("DELETE /menu/{id}", menuHandler.DeleteMenuID)
 
 	// Set up Inventory: repository, service, and handler
-	invRepo := dal.NewJSONInvRepository()
+	invRepo := dal.NewJSONInvRepository(store, locks)
 	invService := service.NewInvService(invRepo)
 	invHandler := handler.NewInvHandler(invService)
 	http.HandleFunc("POST /inventory", invHandler.PostInv)
@@ -52,11 +140,23 @@ func StartServer(port string) error {
 	http.HandleFunc("PUT /inventory/{id}", invHandler.PutInvID)
 	http.HandleFunc("DELETE /inventory/{id}", invHandler.DeleteInvID)
 
+	// Set up Backups: repository, service, and handler
+	backupService := newBackupService(cfg, store, locks, idempotencyService)
+	backupService.StartScheduler(time.Duration(cfg.BackupInterval))
+	backupHandler := handler.NewBackupHandler(backupService)
+	http.HandleFunc("GET /admin/backups", backupHandler.GetBackups)
+	http.HandleFunc("POST /admin/backups", backupHandler.PostBackups)
+	http.HandleFunc("POST /admin/restore", backupHandler.PostRestore)
+
 	// Set up server port and log the server start
-	port = fmt.Sprintf(":%s", port)
+	port := fmt.Sprintf(":%s", cfg.Port)
 	log.Println("Server started on port:", port)
-	// Ensure required JSON files are created
-	CreatedJSONfile()
 	// Start the HTTP server
 	return http.ListenAndServe(port, nil)
 }
+
+// Creates the backup service over the given store with the configured retention policy
+func newBackupService(cfg config.Config, store *dal.Store, locks *dal.FileLocks, idempotency service.IdempotencyService) service.BackupService {
+	backupRepo := dal.NewJSONBackupRepository(cfg, store, locks)
+	return service.NewBackupService(backupRepo, idempotency, cfg.BackupKeepPerDay, cfg.BackupKeepDays)
+}
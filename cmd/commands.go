@@ -0,0 +1,72 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"hot-coffee/internal/config"
+	"hot-coffee/internal/dal"
+	"hot-coffee/internal/service"
+)
+
+// RunCommand runs a command-line subcommand against the data directory. Commands that
+// change data refuse to run while the server is up, since a running server keeps its own
+// copy of the data in memory.
+func RunCommand(cfg config.Config, args []string) error {
+	switch args[0] {
+	case "backups":
+		if len(args) != 1 {
+			return fmt.Errorf("Usage: hot-coffee [options] backups")
+		}
+		backupService, err := openBackupService(cfg)
+		if err != nil {
+			return err
+		}
+		snapshots, err := backupService.ServiceListBackups()
+		if err != nil {
+			return err
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(snapshots)
+	case "restore":
+		if len(args) != 2 {
+			return fmt.Errorf("Usage: hot-coffee [options] restore <snapshot>")
+		}
+		unlock, err := dal.LockDataDir(cfg)
+		if err != nil {
+			return err
+		}
+		defer unlock()
+		backupService, err := openBackupService(cfg)
+		if err != nil {
+			return err
+		}
+		if err := backupService.ServiceRestore(args[1]); err != nil {
+			return err
+		}
+		slog.Info("Restore complete; start the server to use the restored data")
+		return nil
+	default:
+		return fmt.Errorf("Unknown command: %s", args[0])
+	}
+}
+
+// Prepares the data directory and returns a backup service working on it
+func openBackupService(cfg config.Config) (service.BackupService, error) {
+	if err := dal.Bootstrap(cfg); err != nil {
+		return nil, err
+	}
+	store, err := dal.LoadStore(cfg)
+	if err != nil {
+		return nil, err
+	}
+	idempotencyService, err := service.NewIdempotencyService(dal.NewJSONIdempotencyRepository(cfg), time.Duration(cfg.IdempotencyTTL))
+	if err != nil {
+		return nil, err
+	}
+	return newBackupService(cfg, store, dal.NewFileLocks(), idempotencyService), nil
+}
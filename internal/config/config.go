@@ -0,0 +1,425 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Prefix of the environment variables read by Load
+const envPrefix = "HOT_COFFEE_"
+
+// Config holds the server settings. Load resolves them, in increasing order of
+// precedence, from the defaults, an optional JSON config file, environment
+// variables (HOT_COFFEE_<KEY>) and command-line flags.
+type Config struct {
+	Port       string `json:"port"`
+	DataDir    string `json:"data_dir"` // Absolute path of the directory holding the data files.
+	SeedDir    string `json:"seed_dir"` // Absolute path of the directory holding the seed copies used by Seed.
+	IDStrategy string `json:"id_strategy"`
+	Seed       bool   `json:"seed"`
+
+	// Most active orders one customer may have at a time; 0 means no limit
+	MaxOpenOrdersPerCustomer int `json:"max_open_orders_per_customer"`
+	// How long before its pickup time a scheduled order joins the active queue
+	PickupLeadTime Duration `json:"pickup_lead_time"`
+
+	// How long the response to a request with an Idempotency-Key is kept for replay
+	IdempotencyTTL Duration `json:"idempotency_ttl"`
+
+	// Versioned backups: a snapshot is taken every BackupInterval if the data changed,
+	// keeping at most BackupKeepPerDay snapshots for each of the last BackupKeepDays days
+	BackupDir        string   `json:"backup_dir"` // Absolute path of the directory holding the snapshots.
+	BackupInterval   Duration `json:"backup_interval"`
+	BackupKeepPerDay int      `json:"backup_keep_per_day"`
+	BackupKeepDays   int      `json:"backup_keep_days"`
+
+	// File names inside DataDir; an absolute path is used as is
+	OrdersFile        string `json:"orders_file"`
+	MenuFile          string `json:"menu_file"`
+	InventoryFile     string `json:"inventory_file"`
+	CustomersFile     string `json:"customers_file"`
+	LoyaltyRulesFile  string `json:"loyalty_rules_file"`
+	LoyaltyLedgerFile string `json:"loyalty_ledger_file"`
+	PromotionsFile    string `json:"promotions_file"`
+	TaxRatesFile      string `json:"tax_rates_file"`
+	SequenceFile      string `json:"sequence_file"`
+
+	IdempotencyFile string `json:"idempotency_file"`
+}
+
+// Default returns the settings used when nothing else is configured
+func Default() Config {
+	return Config{
+		Port:              "8080",
+		DataDir:           "data",
+		SeedDir:           "reserve_copy",
+		IDStrategy:        "sequence",
+		IdempotencyTTL:    Duration(24 * time.Hour),
+		PickupLeadTime:    Duration(15 * time.Minute),
+		BackupDir:         "backups",
+		BackupInterval:    Duration(5 * time.Minute),
+		BackupKeepPerDay:  24,
+		BackupKeepDays:    7,
+		OrdersFile:        "orders.json",
+		MenuFile:          "menu_items.json",
+		InventoryFile:     "inventory.json",
+		CustomersFile:     "customers.json",
+		LoyaltyRulesFile:  "loyalty_rules.json",
+		LoyaltyLedgerFile: "loyalty_ledger.json",
+		PromotionsFile:    "promotions.json",
+		TaxRatesFile:      "tax_rates.json",
+		SequenceFile:      "order_sequence.json",
+		IdempotencyFile:   "idempotency_keys.json",
+	}
+}
+
+// Duration is a time.Duration written as a string such as "5m" in the config file
+type Duration time.Duration
+
+// UnmarshalJSON parses a duration string such as "90s" or "5m"
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		return err
+	}
+	parsed, err := time.ParseDuration(text)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// MarshalJSON writes the duration as a string such as "5m0s"
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// Load resolves the configuration from the command-line arguments (without the program
+// name), the environment and the config file named by --config or HOT_COFFEE_CONFIG.
+// Relative directories are resolved against the working directory, or against the
+// config file's directory when they come from the file. The arguments left after the
+// flags (a subcommand and its operands) are returned. It returns flag.ErrHelp when
+// help was requested.
+func Load(args []string) (Config, []string, error) {
+	cfg := Default()
+
+	configPath := os.Getenv(envPrefix + "CONFIG")
+	if path, ok := findConfigFlag(args); ok {
+		configPath = path
+	}
+	if configPath != "" {
+		if err := loadFile(configPath, &cfg); err != nil {
+			return cfg, nil, err
+		}
+	}
+	if err := loadEnv(&cfg); err != nil {
+		return cfg, nil, err
+	}
+
+	fs := flag.NewFlagSet("hot-coffee", flag.ContinueOnError)
+	fs.Usage = Usage
+	fs.String("config", configPath, "Path to a JSON config file")
+	fs.StringVar(&cfg.Port, "port", cfg.Port, "Port number")
+	fs.StringVar(&cfg.DataDir, "dir", cfg.DataDir, "Path to the data directory")
+	fs.StringVar(&cfg.SeedDir, "seed-dir", cfg.SeedDir, "Path to the seed copy directory")
+	fs.StringVar(&cfg.IDStrategy, "id-strategy", cfg.IDStrategy, "Order ID strategy: sequence or ulid")
+	fs.BoolVar(&cfg.Seed, "seed", cfg.Seed, "Fill missing data files from the seed copies")
+	fs.IntVar(&cfg.MaxOpenOrdersPerCustomer, "max-open-orders", cfg.MaxOpenOrdersPerCustomer, "Active orders allowed per customer, 0 for no limit")
+	fs.DurationVar((*time.Duration)(&cfg.PickupLeadTime), "pickup-lead-time", time.Duration(cfg.PickupLeadTime), "Time before pickup a scheduled order becomes active")
+	fs.StringVar(&cfg.BackupDir, "backup-dir", cfg.BackupDir, "Path to the snapshot directory")
+	fs.DurationVar((*time.Duration)(&cfg.BackupInterval), "backup-interval", time.Duration(cfg.BackupInterval), "Time between snapshots")
+	fs.IntVar(&cfg.BackupKeepPerDay, "backup-keep-per-day", cfg.BackupKeepPerDay, "Snapshots kept per day")
+	fs.IntVar(&cfg.BackupKeepDays, "backup-keep-days", cfg.BackupKeepDays, "Days of snapshots kept")
+	for _, file := range cfg.fileSettings() {
+		fs.StringVar(file.value, strings.ReplaceAll(file.key, "_", "-"), *file.value, file.description)
+	}
+	fs.DurationVar((*time.Duration)(&cfg.IdempotencyTTL), "idempotency-ttl", time.Duration(cfg.IdempotencyTTL), "Time responses are kept for replay")
+	if err := fs.Parse(args); err != nil {
+		return cfg, nil, err
+	}
+
+	for _, dir := range []*string{&cfg.DataDir, &cfg.SeedDir, &cfg.BackupDir} {
+		abs, err := filepath.Abs(*dir)
+		if err != nil {
+			return cfg, nil, err
+		}
+		*dir = abs
+	}
+	return cfg, fs.Args(), cfg.validate()
+}
+
+// A data file name setting; its flag, environment variable and config file key all follow from key
+type fileSetting struct {
+	key         string // Config file key, such as "orders_file".
+	description string
+	value       *string
+}
+
+// Returns the data file name settings of c, which the flags, the environment and validate go over
+func (c *Config) fileSettings() []fileSetting {
+	return []fileSetting{
+		{"orders_file", "Orders file name", &c.OrdersFile},
+		{"menu_file", "Menu file name", &c.MenuFile},
+		{"inventory_file", "Inventory file name", &c.InventoryFile},
+		{"customers_file", "Customers file name", &c.CustomersFile},
+		{"loyalty_rules_file", "Loyalty rules file name", &c.LoyaltyRulesFile},
+		{"loyalty_ledger_file", "Loyalty ledger file name", &c.LoyaltyLedgerFile},
+		{"promotions_file", "Promotions file name", &c.PromotionsFile},
+		{"tax_rates_file", "Tax rates file name", &c.TaxRatesFile},
+		{"sequence_file", "Order sequence file name", &c.SequenceFile},
+		{"idempotency_file", "Idempotency key file name", &c.IdempotencyFile},
+	}
+}
+
+// Checks that the resolved settings are usable
+func (c Config) validate() error {
+	port, err := strconv.Atoi(c.Port)
+	if err != nil || port < 1024 || port > 65535 {
+		return fmt.Errorf("Port number must be between 1024 and 65535, got %q", c.Port)
+	}
+	for _, file := range c.fileSettings() {
+		if strings.TrimSpace(*file.value) == "" {
+			return fmt.Errorf("Missing %s name", strings.ReplaceAll(file.key, "_", " "))
+		}
+	}
+	if c.MaxOpenOrdersPerCustomer < 0 {
+		return errors.New("Open order limit cannot be negative")
+	}
+	if c.PickupLeadTime < 0 {
+		return errors.New("Pickup lead time cannot be negative")
+	}
+	if c.IdempotencyTTL <= 0 {
+		return errors.New("Idempotency TTL must be positive")
+	}
+	if c.BackupInterval <= 0 {
+		return errors.New("Backup interval must be positive")
+	}
+	if c.BackupKeepPerDay < 1 || c.BackupKeepDays < 1 {
+		return errors.New("Backup retention must keep at least one snapshot per day for at least one day")
+	}
+	return nil
+}
+
+// Returns the value of --config if it is present in args
+func findConfigFlag(args []string) (string, bool) {
+	for i, arg := range args {
+		if arg == "--" {
+			break
+		}
+		name, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if !strings.HasPrefix(arg, "-") || name != "config" {
+			continue
+		}
+		if hasValue {
+			return value, true
+		}
+		if i+1 < len(args) {
+			return args[i+1], true
+		}
+	}
+	return "", false
+}
+
+// Reads a JSON config file over cfg; relative directories in it are relative to the file
+func loadFile(path string, cfg *Config) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("Failed to read config file: %w", err)
+	}
+	var fromFile Config
+	if err := json.Unmarshal(data, &fromFile); err != nil {
+		return fmt.Errorf("Invalid config file %s: %w", path, err)
+	}
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return fmt.Errorf("Invalid config file %s: %w", path, err)
+	}
+	base := filepath.Dir(path)
+	for _, dir := range []struct{ fromFile, resolved *string }{
+		{&fromFile.DataDir, &cfg.DataDir}, {&fromFile.SeedDir, &cfg.SeedDir}, {&fromFile.BackupDir, &cfg.BackupDir},
+	} {
+		if *dir.fromFile != "" && !filepath.IsAbs(*dir.fromFile) {
+			*dir.resolved = filepath.Join(base, *dir.fromFile)
+		}
+	}
+	return nil
+}
+
+// Applies the HOT_COFFEE_* environment variables over cfg
+func loadEnv(cfg *Config) error {
+	stringFields := map[string]*string{
+		"PORT":        &cfg.Port,
+		"DATA_DIR":    &cfg.DataDir,
+		"SEED_DIR":    &cfg.SeedDir,
+		"BACKUP_DIR":  &cfg.BackupDir,
+		"ID_STRATEGY": &cfg.IDStrategy,
+	}
+	for _, file := range cfg.fileSettings() {
+		stringFields[strings.ToUpper(file.key)] = file.value
+	}
+	for key, field := range stringFields {
+		if value, ok := os.LookupEnv(envPrefix + key); ok {
+			*field = value
+		}
+	}
+	boolFields := map[string]*bool{
+		"SEED": &cfg.Seed,
+	}
+	for key, field := range boolFields {
+		if value, ok := os.LookupEnv(envPrefix + key); ok {
+			parsed, err := strconv.ParseBool(value)
+			if err != nil {
+				return fmt.Errorf("Invalid %s%s: %w", envPrefix, key, err)
+			}
+			*field = parsed
+		}
+	}
+	intFields := map[string]*int{
+		"MAX_OPEN_ORDERS":     &cfg.MaxOpenOrdersPerCustomer,
+		"BACKUP_KEEP_PER_DAY": &cfg.BackupKeepPerDay,
+		"BACKUP_KEEP_DAYS":    &cfg.BackupKeepDays,
+	}
+	for key, field := range intFields {
+		if value, ok := os.LookupEnv(envPrefix + key); ok {
+			parsed, err := strconv.Atoi(value)
+			if err != nil {
+				return fmt.Errorf("Invalid %s%s: %w", envPrefix, key, err)
+			}
+			*field = parsed
+		}
+	}
+	durationFields := map[string]*Duration{
+		"BACKUP_INTERVAL":  &cfg.BackupInterval,
+		"IDEMPOTENCY_TTL":  &cfg.IdempotencyTTL,
+		"PICKUP_LEAD_TIME": &cfg.PickupLeadTime,
+	}
+	for key, field := range durationFields {
+		if value, ok := os.LookupEnv(envPrefix + key); ok {
+			parsed, err := time.ParseDuration(value)
+			if err != nil {
+				return fmt.Errorf("Invalid %s%s: %w", envPrefix, key, err)
+			}
+			*field = Duration(parsed)
+		}
+	}
+	return nil
+}
+
+// DataPath returns the full path of a data file name; an absolute name is used as is
+func (c Config) DataPath(name string) string {
+	if filepath.IsAbs(name) {
+		return name
+	}
+	return filepath.Join(c.DataDir, name)
+}
+
+// OrdersPath returns the full path to the orders file
+func (c Config) OrdersPath() string {
+	return c.DataPath(c.OrdersFile)
+}
+
+// MenuPath returns the full path to the menu items file
+func (c Config) MenuPath() string {
+	return c.DataPath(c.MenuFile)
+}
+
+// InventoryPath returns the full path to the inventory file
+func (c Config) InventoryPath() string {
+	return c.DataPath(c.InventoryFile)
+}
+
+// SequencePath returns the full path to the file holding the last issued order number
+func (c Config) SequencePath() string {
+	return c.DataPath(c.SequenceFile)
+}
+
+// IdempotencyPath returns the full path to the file holding the stored idempotent responses
+func (c Config) IdempotencyPath() string {
+	return c.DataPath(c.IdempotencyFile)
+}
+
+// SeedMenuPath returns the full path to the seed copy of the menu
+func (c Config) SeedMenuPath() string {
+	return filepath.Join(c.SeedDir, filepath.Base(c.MenuFile))
+}
+
+// SeedInventoryPath returns the full path to the seed copy of the inventory
+func (c Config) SeedInventoryPath() string {
+	return filepath.Join(c.SeedDir, filepath.Base(c.InventoryFile))
+}
+
+// SeedOrdersPath returns the full path to the seed copy of the orders for the given day (YYYY-MM-DD)
+func (c Config) SeedOrdersPath(day string) string {
+	return filepath.Join(c.SeedDir, fmt.Sprintf("%s_%s", day, filepath.Base(c.OrdersFile)))
+}
+
+// Usage prints the help text for the command-line options
+func Usage() {
+	fmt.Println(
+		`Coffee Shop Management System
+
+Usage:
+	hot-coffee [options]                       Start the server.
+	hot-coffee [options] backups               List the backup snapshots.
+	hot-coffee [options] restore <snapshot>    Restore a snapshot while the server is stopped.
+	hot-coffee --help
+
+Options:
+	--help                   Show this screen.
+	--port N                 Port number.
+	--dir S                  Path to the data directory.
+	--config S               Path to a JSON config file.
+	--id-strategy S          Order ID strategy: "sequence" (default) or "ulid".
+	--seed                   Fill missing or empty data files from the seed copies.
+	--seed-dir S             Path to the seed copy directory.
+	--max-open-orders N      Active orders allowed per customer, 0 (default) for no limit.
+	--pickup-lead-time D     Time before pickup a scheduled order joins the active queue.
+	--backup-dir S           Path to the snapshot directory.
+	--backup-interval D      Time between snapshots, taken only if the data changed.
+	--backup-keep-per-day N  Snapshots kept for each day.
+	--backup-keep-days N     Number of most recent days with snapshots kept.
+	--orders-file S          Orders file name inside the data directory.
+	--menu-file S            Menu file name inside the data directory.
+	--inventory-file S       Inventory file name inside the data directory.
+	--customers-file S       Customers file name inside the data directory.
+	--loyalty-rules-file S   Loyalty rules file name inside the data directory.
+	--loyalty-ledger-file S  Loyalty ledger file name inside the data directory.
+	--promotions-file S      Promotions file name inside the data directory.
+	--tax-rates-file S       Tax rates file name inside the data directory.
+	--sequence-file S        Order sequence file name inside the data directory.
+	--idempotency-file S     Idempotency key file name inside the data directory.
+	--idempotency-ttl D      Time the response to an Idempotency-Key is kept for replay.
+
+Options can also be set with environment variables or config file keys; flags take
+precedence over the environment, which takes precedence over the config file:
+	HOT_COFFEE_CONFIG                                Same as --config.
+	HOT_COFFEE_PORT                 port             Same as --port.
+	HOT_COFFEE_DATA_DIR             data_dir         Same as --dir.
+	HOT_COFFEE_ID_STRATEGY          id_strategy      Same as --id-strategy.
+	HOT_COFFEE_SEED                 seed             Same as --seed.
+	HOT_COFFEE_SEED_DIR             seed_dir         Same as --seed-dir.
+	HOT_COFFEE_MAX_OPEN_ORDERS      max_open_orders_per_customer
+	HOT_COFFEE_PICKUP_LEAD_TIME     pickup_lead_time
+	HOT_COFFEE_BACKUP_DIR           backup_dir       Same as --backup-dir.
+	HOT_COFFEE_BACKUP_INTERVAL      backup_interval  Same as --backup-interval.
+	HOT_COFFEE_BACKUP_KEEP_PER_DAY  backup_keep_per_day
+	HOT_COFFEE_BACKUP_KEEP_DAYS     backup_keep_days
+	HOT_COFFEE_ORDERS_FILE          orders_file
+	HOT_COFFEE_MENU_FILE            menu_file
+	HOT_COFFEE_INVENTORY_FILE       inventory_file
+	HOT_COFFEE_CUSTOMERS_FILE       customers_file
+	HOT_COFFEE_LOYALTY_RULES_FILE   loyalty_rules_file
+	HOT_COFFEE_LOYALTY_LEDGER_FILE  loyalty_ledger_file
+	HOT_COFFEE_PROMOTIONS_FILE      promotions_file
+	HOT_COFFEE_TAX_RATES_FILE       tax_rates_file
+	HOT_COFFEE_SEQUENCE_FILE        sequence_file
+	HOT_COFFEE_IDEMPOTENCY_FILE     idempotency_file
+	HOT_COFFEE_IDEMPOTENCY_TTL      idempotency_ttl`)
+}
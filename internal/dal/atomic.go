@@ -0,0 +1,105 @@
+package dal
+
+import (
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Marker placed in the names of temporary files created by WriteFileAtomic
+const tempFileMarker = ".tmp-"
+
+// WriteFileAtomic replaces the file at path with data so that readers only ever see
+// the old or the new content: the data goes to a temp file in the same directory,
+// which is synced, renamed over the target, and the directory itself is synced.
+func WriteFileAtomic(path string, data []byte) error {
+	tmpName, err := writeTemp(path, data)
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(tmpName, path); err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+	return syncDir(filepath.Dir(path))
+}
+
+// WriteJSONAtomic encodes v as indented JSON and writes it to path with WriteFileAtomic
+func WriteJSONAtomic(path string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+	return WriteFileAtomic(path, data)
+}
+
+// Flushes directory metadata (such as a rename) to disk
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
+
+// RecoverTempFiles cleans up temp files left in dir by an interrupted WriteFileAtomic.
+// A temp file is only renamed after it has been fully synced, so a leftover one means
+// the target still holds its previous content and the temp file is discarded. The one
+// exception is a target that is missing or empty while the temp file holds valid JSON:
+// then the temp file is the only copy of the data and it is promoted to the target.
+// An interrupted transaction journaled in dir is completed before any cleanup.
+func RecoverTempFiles(dir string) error {
+	if err := recoverTransaction(dir); err != nil {
+		return err
+	}
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, ".") || !strings.Contains(name, tempFileMarker) {
+			continue
+		}
+		tmpPath := filepath.Join(dir, name)
+		target := filepath.Join(dir, strings.TrimPrefix(name[:strings.LastIndex(name, tempFileMarker)], "."))
+
+		if promote, err := shouldPromote(tmpPath, target); err != nil {
+			return err
+		} else if promote {
+			if err := os.Rename(tmpPath, target); err != nil {
+				return err
+			}
+			slog.Warn("Recovered data file from interrupted write", slog.String("file", target))
+			continue
+		}
+		if err := os.Remove(tmpPath); err != nil {
+			return err
+		}
+		slog.Warn("Removed leftover temp file", slog.String("file", tmpPath))
+	}
+	return syncDir(dir)
+}
+
+// Reports whether a leftover temp file should replace its target file
+func shouldPromote(tmpPath, target string) (bool, error) {
+	info, err := os.Stat(target)
+	if err != nil && !os.IsNotExist(err) {
+		return false, err
+	}
+	if err == nil && info.Size() > 0 {
+		return false, nil
+	}
+	data, err := os.ReadFile(tmpPath)
+	if err != nil {
+		return false, err
+	}
+	return len(data) > 0 && json.Valid(data), nil
+}
@@ -0,0 +1,153 @@
+package dal
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Writes content to a file in dir, failing the test on error
+func writeTestFile(t *testing.T, dir string, name string, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// Returns the content of a file, or "<missing>" when it does not exist
+func readTestFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return "<missing>"
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+// Returns the names of the temp files left in dir
+func tempFiles(t *testing.T, dir string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, entry := range entries {
+		if strings.Contains(entry.Name(), tempFileMarker) {
+			names = append(names, entry.Name())
+		}
+	}
+	return names
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := writeTestFile(t, dir, "orders.json", `["old"]`)
+	if err := WriteFileAtomic(path, []byte(`["new"]`)); err != nil {
+		t.Fatal(err)
+	}
+	if got := readTestFile(t, path); got != `["new"]` {
+		t.Errorf("file holds %s, want the new content", got)
+	}
+	if temps := tempFiles(t, dir); len(temps) != 0 {
+		t.Errorf("temp files left behind: %v", temps)
+	}
+}
+
+// A write killed after its temp file is synced but before the rename leaves the temp file
+// behind; recovery keeps the target unless the temp file is the only copy of the data
+func TestRecoverTempFiles(t *testing.T) {
+	tests := []struct {
+		name       string
+		target     string // Content of the target before recovery; "<missing>" for none.
+		temp       string // Content of the leftover temp file.
+		wantTarget string
+	}{
+		{"target intact", `["old"]`, `["new"]`, `["old"]`},
+		{"target missing", "<missing>", `["new"]`, `["new"]`},
+		{"target empty", "", `["new"]`, `["new"]`},
+		{"torn temp, target missing", "<missing>", `["ne`, "<missing>"},
+		{"torn temp, target empty", "", `["ne`, ""},
+		{"empty temp, target missing", "<missing>", "", "<missing>"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			target := filepath.Join(dir, "orders.json")
+			if test.target != "<missing>" {
+				writeTestFile(t, dir, "orders.json", test.target)
+			}
+			if _, err := writeTemp(target, []byte(test.temp)); err != nil {
+				t.Fatal(err)
+			}
+			if err := RecoverTempFiles(dir); err != nil {
+				t.Fatal(err)
+			}
+			if got := readTestFile(t, target); got != test.wantTarget {
+				t.Errorf("target holds %q, want %q", got, test.wantTarget)
+			}
+			if temps := tempFiles(t, dir); len(temps) != 0 {
+				t.Errorf("temp files left behind: %v", temps)
+			}
+		})
+	}
+}
+
+// Files that only look like temp files are left alone, and a missing directory is not an error
+func TestRecoverTempFilesLeavesOtherFiles(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"orders.json":        `["old"]`,
+		".hidden.json":       `[]`,
+		"notes.tmp-1.json":   `[]`,
+		"inventory.json.bak": `[]`,
+	}
+	for name, content := range files {
+		writeTestFile(t, dir, name, content)
+	}
+	if err := RecoverTempFiles(dir); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		if got := readTestFile(t, filepath.Join(dir, name)); got != content {
+			t.Errorf("%s holds %q, want %q", name, got, content)
+		}
+	}
+	if err := RecoverTempFiles(filepath.Join(dir, "missing")); err != nil {
+		t.Errorf("recovering a missing directory: %v", err)
+	}
+}
+
+func TestShouldPromote(t *testing.T) {
+	dir := t.TempDir()
+	valid := writeTestFile(t, dir, ".a.json.tmp-1", `{"ok":true}`)
+	torn := writeTestFile(t, dir, ".b.json.tmp-1", `{"ok":`)
+	full := writeTestFile(t, dir, "full.json", `[]`)
+	empty := writeTestFile(t, dir, "empty.json", "")
+	missing := filepath.Join(dir, "missing.json")
+	tests := []struct {
+		temp, target string
+		want         bool
+	}{
+		{valid, full, false},
+		{valid, empty, true},
+		{valid, missing, true},
+		{torn, empty, false},
+		{torn, missing, false},
+	}
+	for _, test := range tests {
+		got, err := shouldPromote(test.temp, test.target)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != test.want {
+			t.Errorf("shouldPromote(%s, %s) = %v, want %v", filepath.Base(test.temp), filepath.Base(test.target), got, test.want)
+		}
+	}
+}
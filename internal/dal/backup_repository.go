@@ -0,0 +1,267 @@
+package dal
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"hot-coffee/internal/config"
+	"hot-coffee/models"
+)
+
+// Layout of snapshot IDs; they name the snapshot directories and sort chronologically
+const snapshotIDLayout = "20060102T150405.000Z"
+
+// Name of the file describing a snapshot inside its directory
+const snapshotManifest = "snapshot.json"
+
+// ErrSnapshotNotFound is returned for a snapshot ID that does not name an existing snapshot
+var ErrSnapshotNotFound = errors.New("Snapshot not found")
+
+// BackupRepository stores versioned snapshots of the data files.
+// Each snapshot is a directory in the backup directory holding a copy of the
+// data files and a manifest; it is written under a temp name and renamed into place,
+// so a listed snapshot is always complete.
+type BackupRepository interface {
+	Locker
+	DataVersion() uint64                      // Changes whenever the data changes.
+	CreateSnapshot() (models.Snapshot, error) // Copies the current data into a new snapshot.
+	ListSnapshots() ([]models.Snapshot, error)
+	DeleteSnapshot(id string) error
+	RestoreSnapshot(id string) error // Replaces the data with a snapshot in one transaction.
+}
+
+type jsonBackupRepository struct {
+	*FileLocks
+	cfg    config.Config
+	store  *Store
+	mu     sync.Mutex // Keeps snapshot IDs unique.
+	lastID string
+}
+
+// Creates and returns a new instance of jsonBackupRepository
+func NewJSONBackupRepository(cfg config.Config, store *Store, locks *FileLocks) BackupRepository {
+	return &jsonBackupRepository{FileLocks: locks, cfg: cfg, store: store}
+}
+
+// Returns the version of the data held by the store
+func (r *jsonBackupRepository) DataVersion() uint64 {
+	return r.store.Version()
+}
+
+// Writes the current data files to a new snapshot directory
+func (r *jsonBackupRepository) CreateSnapshot() (models.Snapshot, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now().UTC()
+	id := now.Format(snapshotIDLayout)
+	for id <= r.lastID {
+		now = now.Add(time.Millisecond)
+		id = now.Format(snapshotIDLayout)
+	}
+	contents := r.store.contents()
+	snapshot := models.Snapshot{
+		ID:        id,
+		CreatedAt: now.Local().Format("2006-01-02 15:04:05"),
+		Counts:    make(map[string]int, len(storeFiles)),
+	}
+	files := make(map[string]any, len(storeFiles)+1)
+	for _, file := range storeFiles {
+		snapshot.Counts[file.name()] = file.length(contents[file.name()])
+		files[file.baseName(r.cfg)] = contents[file.name()]
+	}
+	files[snapshotManifest] = snapshot
+
+	if err := os.MkdirAll(r.cfg.BackupDir, 0o755); err != nil {
+		return snapshot, err
+	}
+	tmpDir, err := os.MkdirTemp(r.cfg.BackupDir, "."+id+tempFileMarker+"*")
+	if err != nil {
+		return snapshot, err
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			os.RemoveAll(tmpDir)
+		}
+	}()
+
+	for name, content := range files {
+		if err := WriteJSONAtomic(filepath.Join(tmpDir, name), content); err != nil {
+			return snapshot, err
+		}
+	}
+	// The order sequence and the idempotency store are kept outside the store; they are copied as they are on disk
+	for _, path := range []string{r.cfg.SequencePath(), r.cfg.IdempotencyPath()} {
+		if err := copyFileIfExists(path, filepath.Join(tmpDir, filepath.Base(path))); err != nil {
+			return snapshot, err
+		}
+	}
+	if err := os.Chmod(tmpDir, 0o755); err != nil {
+		return snapshot, err
+	}
+	if err := os.Rename(tmpDir, filepath.Join(r.cfg.BackupDir, id)); err != nil {
+		return snapshot, err
+	}
+	committed = true
+	r.lastID = id
+	return snapshot, syncDir(r.cfg.BackupDir)
+}
+
+// Returns all complete snapshots, newest first
+func (r *jsonBackupRepository) ListSnapshots() ([]models.Snapshot, error) {
+	snapshots := []models.Snapshot{}
+	entries, err := os.ReadDir(r.cfg.BackupDir)
+	if os.IsNotExist(err) {
+		return snapshots, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() || !validSnapshotID(entry.Name()) {
+			continue
+		}
+		var snapshot models.Snapshot
+		if err := readJSONFile(filepath.Join(r.cfg.BackupDir, entry.Name(), snapshotManifest), &snapshot); err != nil {
+			return nil, fmt.Errorf("Unreadable snapshot %s: %w", entry.Name(), err)
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+	slices.SortFunc(snapshots, func(a, b models.Snapshot) int {
+		return strings.Compare(b.ID, a.ID)
+	})
+	return snapshots, nil
+}
+
+// Removes a snapshot directory
+func (r *jsonBackupRepository) DeleteSnapshot(id string) error {
+	dir, err := r.snapshotDir(id)
+	if err != nil {
+		return err
+	}
+	return os.RemoveAll(dir)
+}
+
+// Validates the data files of a snapshot and commits them over the live data together.
+// Files added after a snapshot was taken are missing from it and are left as they are. The order sequence never moves back, so IDs
+// issued after the snapshot are not issued again, and the idempotency store is replaced
+// by the snapshot's, or emptied if the snapshot has none, since it answers for the orders
+// being replaced.
+func (r *jsonBackupRepository) RestoreSnapshot(id string) error {
+	dir, err := r.snapshotDir(id)
+	if err != nil {
+		return err
+	}
+	restored := make(map[string]any, len(storeFiles))
+	for _, file := range storeFiles {
+		data, err := os.ReadFile(filepath.Join(dir, file.baseName(r.cfg)))
+		if os.IsNotExist(err) && file.missingFromOldSnapshots() {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		entries, err := file.decode(data)
+		if err != nil {
+			return fmt.Errorf("Snapshot file %s is corrupt: %v", file.baseName(r.cfg), err)
+		}
+		restored[file.name()] = entries
+	}
+
+	sequence, err := r.restoredSequence(dir)
+	if err != nil {
+		return err
+	}
+	responses := []models.IdempotentResponse{}
+	if err := readJSONFile(filepath.Join(dir, filepath.Base(r.cfg.IdempotencyPath())), &responses); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("Snapshot file %s is corrupt: %v", filepath.Base(r.cfg.IdempotencyPath()), err)
+	}
+
+	tx := r.store.Begin()
+	defer tx.Rollback()
+	if err := tx.stageFile(r.cfg.SequencePath(), sequenceFile{LastID: sequence}); err != nil {
+		return err
+	}
+	if err := tx.stageFile(r.cfg.IdempotencyPath(), responses); err != nil {
+		return err
+	}
+	for _, file := range storeFiles {
+		if entries, exists := restored[file.name()]; exists {
+			if err := file.stage(tx, entries); err != nil {
+				return err
+			}
+		}
+	}
+	return tx.Commit()
+}
+
+// Returns the order sequence to restore: the higher of the live one and the snapshot's
+func (r *jsonBackupRepository) restoredSequence(dir string) (int, error) {
+	live, err := NewJSONSequenceRepository(r.cfg).ReadSequence()
+	if err != nil {
+		return 0, err
+	}
+	var saved sequenceFile
+	if err := readJSONFile(filepath.Join(dir, filepath.Base(r.cfg.SequencePath())), &saved); err != nil && !os.IsNotExist(err) {
+		return 0, fmt.Errorf("Snapshot file %s is corrupt: %v", filepath.Base(r.cfg.SequencePath()), err)
+	}
+	return max(live, saved.LastID), nil
+}
+
+// Copies the file at src to dst atomically; a missing src is not an error
+func copyFileIfExists(src, dst string) error {
+	data, err := os.ReadFile(src)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return WriteFileAtomic(dst, data)
+}
+
+// Returns the directory of an existing snapshot
+func (r *jsonBackupRepository) snapshotDir(id string) (string, error) {
+	if !validSnapshotID(id) {
+		return "", ErrSnapshotNotFound
+	}
+	dir := filepath.Join(r.cfg.BackupDir, id)
+	if _, err := os.Stat(filepath.Join(dir, snapshotManifest)); os.IsNotExist(err) {
+		return "", ErrSnapshotNotFound
+	} else if err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+// Reports whether id has the form of a snapshot ID, which also keeps it from escaping the backup directory
+func validSnapshotID(id string) bool {
+	_, err := time.Parse(snapshotIDLayout, id)
+	return err == nil
+}
+
+// Removes snapshot directories left incomplete by an interrupted CreateSnapshot
+func removeIncompleteSnapshots(backupDir string) error {
+	entries, err := os.ReadDir(backupDir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() && strings.HasPrefix(entry.Name(), ".") && strings.Contains(entry.Name(), tempFileMarker) {
+			if err := os.RemoveAll(filepath.Join(backupDir, entry.Name())); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
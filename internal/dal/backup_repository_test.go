@@ -0,0 +1,136 @@
+package dal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"hot-coffee/models"
+)
+
+// Snapshots carry the order sequence and the idempotency store; a restore brings back the
+// snapshot's stored responses but never moves the sequence back
+func TestSnapshotSequenceAndIdempotency(t *testing.T) {
+	store, cfg := newTestStore(t)
+	repo := NewJSONBackupRepository(cfg, store, NewFileLocks())
+	seqRepo := NewJSONSequenceRepository(cfg)
+	idemRepo := NewJSONIdempotencyRepository(cfg)
+	before := []models.IdempotentResponse{{Key: "before", Request: "POST /orders", Status: 201, CreatedAt: time.Now()}}
+	if err := seqRepo.WriteSequence(3); err != nil {
+		t.Fatal(err)
+	}
+	if err := idemRepo.WriteResponses(before); err != nil {
+		t.Fatal(err)
+	}
+	snapshot, err := repo.CreateSnapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{cfg.SequencePath(), cfg.IdempotencyPath()} {
+		if _, err := os.Stat(filepath.Join(cfg.BackupDir, snapshot.ID, filepath.Base(path))); err != nil {
+			t.Errorf("snapshot lacks %s: %v", filepath.Base(path), err)
+		}
+	}
+
+	if err := seqRepo.WriteSequence(9); err != nil {
+		t.Fatal(err)
+	}
+	if err := idemRepo.WriteResponses(append(before, models.IdempotentResponse{Key: "after", Request: "POST /orders", Status: 201, CreatedAt: time.Now()})); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.RestoreSnapshot(snapshot.ID); err != nil {
+		t.Fatal(err)
+	}
+	if last, err := seqRepo.ReadSequence(); err != nil || last != 9 {
+		t.Errorf("sequence after restore is %d, %v, want 9", last, err)
+	}
+	responses, err := idemRepo.ReadResponses()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(responses) != 1 || responses[0].Key != "before" {
+		t.Errorf("stored responses after restore are %+v, want the snapshot's", responses)
+	}
+}
+
+// A snapshot taken before the sequence and the idempotency store were backed up restores
+// with the live sequence and no stored responses
+func TestRestoreSnapshotWithoutSequenceAndIdempotency(t *testing.T) {
+	store, cfg := newTestStore(t)
+	repo := NewJSONBackupRepository(cfg, store, NewFileLocks())
+	snapshot, err := repo.CreateSnapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{cfg.SequencePath(), cfg.IdempotencyPath()} {
+		os.Remove(filepath.Join(cfg.BackupDir, snapshot.ID, filepath.Base(path)))
+	}
+	if err := NewJSONSequenceRepository(cfg).WriteSequence(5); err != nil {
+		t.Fatal(err)
+	}
+	if err := NewJSONIdempotencyRepository(cfg).WriteResponses([]models.IdempotentResponse{{Key: "k", CreatedAt: time.Now()}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.RestoreSnapshot(snapshot.ID); err != nil {
+		t.Fatal(err)
+	}
+	if last, err := NewJSONSequenceRepository(cfg).ReadSequence(); err != nil || last != 5 {
+		t.Errorf("sequence after restore is %d, %v, want 5", last, err)
+	}
+	if responses, err := NewJSONIdempotencyRepository(cfg).ReadResponses(); err != nil || len(responses) != 0 {
+		t.Errorf("stored responses after restore are %+v, %v, want none", responses, err)
+	}
+}
+
+// Every file in storeFiles is copied into a snapshot and counted in its manifest, and a
+// restore brings them all back except those missing from an older snapshot
+func TestSnapshotCoversStoreFiles(t *testing.T) {
+	store, cfg := newTestStore(t)
+	repo := NewJSONBackupRepository(cfg, store, NewFileLocks())
+	if err := store.SaveOrders([]models.Order{{ID: "1", CustomerName: "Ada"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SaveCustomers([]models.Customer{{ID: "1", Name: "Ada"}}); err != nil {
+		t.Fatal(err)
+	}
+	snapshot, err := repo.CreateSnapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var manifest map[string]any
+	if err := readJSONFile(filepath.Join(cfg.BackupDir, snapshot.ID, snapshotManifest), &manifest); err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range storeFiles {
+		if _, err := os.Stat(filepath.Join(cfg.BackupDir, snapshot.ID, file.baseName(cfg))); err != nil {
+			t.Errorf("snapshot lacks %s: %v", file.baseName(cfg), err)
+		}
+		if _, counted := manifest[file.name()]; !counted {
+			t.Errorf("manifest %v does not count %s", manifest, file.name())
+		}
+	}
+	if manifest["orders"] != 1.0 || manifest["customers"] != 1.0 || manifest["snapshot_id"] != snapshot.ID {
+		t.Errorf("manifest is %v, want one order and one customer in snapshot %s", manifest, snapshot.ID)
+	}
+
+	// A snapshot from before the customers existed leaves the live customers in place
+	if err := os.Remove(filepath.Join(cfg.BackupDir, snapshot.ID, customersData.baseName(cfg))); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SaveOrders(nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SaveCustomers([]models.Customer{{ID: "1", Name: "Ada"}, {ID: "2", Name: "Grace"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.RestoreSnapshot(snapshot.ID); err != nil {
+		t.Fatal(err)
+	}
+	if orders := store.Orders(); len(orders) != 1 || orders[0].ID != "1" {
+		t.Errorf("orders after restore are %+v, want the snapshot's", orders)
+	}
+	if customers := store.Customers(); len(customers) != 2 {
+		t.Errorf("customers after restore are %+v, want the live ones", customers)
+	}
+}
@@ -0,0 +1,131 @@
+package dal
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"hot-coffee/internal/config"
+)
+
+// A data file managed by Bootstrap
+type dataFile struct {
+	path     string
+	validate func(data []byte) error
+	seedFrom func() (string, error) // Returns the seed file to copy from, or "" if there is none.
+}
+
+// Bootstrap prepares the data directory before the store is loaded: it recovers from
+// interrupted writes and snapshots, creates missing data files (empty, or seeded from the seed
+// copies when cfg.Seed is set) and validates existing ones. Existing data is never
+// overwritten; a corrupt file stops startup with an error naming it.
+func Bootstrap(cfg config.Config) error {
+	if err := os.MkdirAll(cfg.DataDir, 0o755); err != nil {
+		return err
+	}
+	for _, dir := range []string{cfg.DataDir, cfg.SeedDir} {
+		if err := RecoverTempFiles(dir); err != nil {
+			return fmt.Errorf("Failed to recover interrupted writes in %s: %w", dir, err)
+		}
+	}
+	if err := removeIncompleteSnapshots(cfg.BackupDir); err != nil {
+		return fmt.Errorf("Failed to clean up the backup directory: %w", err)
+	}
+
+	for _, file := range storeFiles {
+		if err := prepareDataFile(file.dataFile(cfg), cfg.Seed); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Creates, seeds or validates a single data file
+func prepareDataFile(file dataFile, seed bool) error {
+	data, err := os.ReadFile(file.path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if len(data) > 0 {
+		if err := file.validate(data); err != nil {
+			return fmt.Errorf("Data file %s is corrupt: %v. Repair or restore it, or move it away and start with --seed", file.path, err)
+		}
+		return nil
+	}
+
+	if seed {
+		source, err := file.seedFrom()
+		if err != nil {
+			return err
+		}
+		if source != "" {
+			content, err := os.ReadFile(source)
+			if err != nil {
+				return err
+			}
+			if err := file.validate(content); err != nil {
+				return fmt.Errorf("Seed copy %s is corrupt: %v", source, err)
+			}
+			slog.Info("Seeded data file", slog.String("file", file.path), slog.String("from", source))
+			return WriteFileAtomic(file.path, content)
+		}
+		slog.Warn("No seed copy to seed from", slog.String("file", file.path))
+	}
+	slog.Info("Created empty data file", slog.String("file", file.path))
+	return WriteFileAtomic(file.path, []byte("[]\n"))
+}
+
+// Returns a validator that decodes a JSON list of T and rejects empty or duplicate IDs
+func validateIDs[T any](idOf func(T) string) func(data []byte) error {
+	return func(data []byte) error {
+		var items []T
+		if err := json.Unmarshal(data, &items); err != nil {
+			return err
+		}
+		seen := make(map[string]bool, len(items))
+		for i, item := range items {
+			id := idOf(item)
+			if strings.TrimSpace(id) == "" {
+				return fmt.Errorf("entry %d has no ID", i)
+			}
+			if seen[id] {
+				return fmt.Errorf("duplicate ID %q", id)
+			}
+			seen[id] = true
+		}
+		return nil
+	}
+}
+
+// A seed source for files that have no seed copy
+func noSeed() (string, error) {
+	return "", nil
+}
+
+// Returns a seed source that uses path if it exists
+func seedFile(path string) func() (string, error) {
+	return func() (string, error) {
+		exists, err := FileExistsInDirectory(path)
+		if err != nil || !exists {
+			return "", err
+		}
+		return path, nil
+	}
+}
+
+// Returns a seed source that uses the most recent dated seed copy of the orders
+func latestSeedOrders(cfg config.Config) func() (string, error) {
+	return func() (string, error) {
+		matches, err := filepath.Glob(cfg.SeedOrdersPath("*"))
+		if err != nil || len(matches) == 0 {
+			return "", err
+		}
+		// Names start with the date in YYYY-MM-DD form, so they sort chronologically
+		slices.Sort(matches)
+		return matches[len(matches)-1], nil
+	}
+}
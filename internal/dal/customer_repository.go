@@ -0,0 +1,51 @@
+package dal
+
+import (
+	"hot-coffee/models"
+)
+
+// CustomerRepository defines the methods for reading and writing customer data.
+type CustomerRepository interface {
+	Locker
+	ReadCustomers() ([]models.Customer, error)        // Reads all customers.
+	FindCustomer(id string) (models.Customer, bool)   // Looks up one customer by ID.
+	WriteCustomers(customers []models.Customer) error // Writes the updated customers to the JSON file.
+	ReadOrders() ([]models.Order, error)              // Reads the orders, to total them per customer.
+	ReadLedger() ([]models.LoyaltyEntry, error)       // Reads the loyalty ledger.
+}
+
+// jsonCustomerRepository implements the CustomerRepository interface using JSON file storage.
+type jsonCustomerRepository struct {
+	*FileLocks        // Locks shared with the other repositories.
+	store      *Store // In-memory copy of the data files.
+}
+
+// NewJSONCustomerRepository creates and returns a new instance of jsonCustomerRepository.
+func NewJSONCustomerRepository(store *Store, locks *FileLocks) CustomerRepository {
+	return &jsonCustomerRepository{FileLocks: locks, store: store}
+}
+
+// ReadCustomers returns the customers held in the in-memory store.
+func (r *jsonCustomerRepository) ReadCustomers() ([]models.Customer, error) {
+	return r.store.Customers(), nil
+}
+
+// FindCustomer returns the customer with the given ID and whether it exists.
+func (r *jsonCustomerRepository) FindCustomer(id string) (models.Customer, bool) {
+	return r.store.Customer(id)
+}
+
+// WriteCustomers writes the customers to the JSON file and the in-memory store.
+func (r *jsonCustomerRepository) WriteCustomers(customers []models.Customer) error {
+	return r.store.SaveCustomers(customers)
+}
+
+// ReadLedger returns the loyalty ledger held in the in-memory store.
+func (r *jsonCustomerRepository) ReadLedger() ([]models.LoyaltyEntry, error) {
+	return r.store.Ledger(), nil
+}
+
+// ReadOrders returns the orders held in the in-memory store.
+func (r *jsonCustomerRepository) ReadOrders() ([]models.Order, error) {
+	return r.store.Orders(), nil
+}
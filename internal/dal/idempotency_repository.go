@@ -0,0 +1,38 @@
+package dal
+
+import (
+	"os"
+
+	"hot-coffee/internal/config"
+	"hot-coffee/models"
+)
+
+// IdempotencyRepository persists the stored responses to requests made with an Idempotency-Key
+type IdempotencyRepository interface {
+	ReadResponses() ([]models.IdempotentResponse, error)
+	WriteResponses(responses []models.IdempotentResponse) error
+}
+
+type jsonIdempotencyRepository struct {
+	cfg config.Config
+}
+
+// Creates and returns a new instance of jsonIdempotencyRepository
+func NewJSONIdempotencyRepository(cfg config.Config) IdempotencyRepository {
+	return &jsonIdempotencyRepository{cfg: cfg}
+}
+
+// Reads the stored responses, returning none if the file does not exist yet
+func (r *jsonIdempotencyRepository) ReadResponses() ([]models.IdempotentResponse, error) {
+	responses := []models.IdempotentResponse{}
+	err := readJSONFile(r.cfg.IdempotencyPath(), &responses)
+	if os.IsNotExist(err) {
+		return responses, nil
+	}
+	return responses, err
+}
+
+// Atomically replaces the stored responses
+func (r *jsonIdempotencyRepository) WriteResponses(responses []models.IdempotentResponse) error {
+	return WriteJSONAtomic(r.cfg.IdempotencyPath(), responses)
+}
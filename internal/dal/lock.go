@@ -0,0 +1,72 @@
+package dal
+
+import (
+	"slices"
+	"sync"
+)
+
+// Locker serializes access to data files across all repositories sharing the same FileLocks.
+// Files are identified by their logical names (OrdersFile, MenuItemFile, InventoryitemFile and the others in common.go).
+type Locker interface {
+	Lock(files ...string) (unlock func())  // Exclusive access for read-modify-write sequences.
+	RLock(files ...string) (unlock func()) // Shared access for a consistent read of several files.
+}
+
+// FileLocks holds one read-write lock per data file. Locks for several files are
+// always taken in sorted order, so callers that lock in a single call cannot deadlock.
+// Locks are not reentrant: a caller must not lock again while holding a lock.
+type FileLocks struct {
+	mu    sync.Mutex
+	locks map[string]*sync.RWMutex
+}
+
+// NewFileLocks creates an empty set of file locks to be shared by the repositories
+func NewFileLocks() *FileLocks {
+	return &FileLocks{locks: make(map[string]*sync.RWMutex)}
+}
+
+// Lock takes the exclusive lock of every listed file and returns a function releasing them
+func (l *FileLocks) Lock(files ...string) func() {
+	mutexes := l.mutexes(files)
+	for _, m := range mutexes {
+		m.Lock()
+	}
+	return func() {
+		for i := len(mutexes) - 1; i >= 0; i-- {
+			mutexes[i].Unlock()
+		}
+	}
+}
+
+// RLock takes the shared lock of every listed file and returns a function releasing them
+func (l *FileLocks) RLock(files ...string) func() {
+	mutexes := l.mutexes(files)
+	for _, m := range mutexes {
+		m.RLock()
+	}
+	return func() {
+		for i := len(mutexes) - 1; i >= 0; i-- {
+			mutexes[i].RUnlock()
+		}
+	}
+}
+
+// Returns the locks of the given files in sorted order, without duplicates
+func (l *FileLocks) mutexes(files []string) []*sync.RWMutex {
+	names := slices.Clone(files)
+	slices.Sort(names)
+	names = slices.Compact(names)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	result := make([]*sync.RWMutex, 0, len(names))
+	for _, name := range names {
+		m, exists := l.locks[name]
+		if !exists {
+			m = &sync.RWMutex{}
+			l.locks[name] = m
+		}
+		result = append(result, m)
+	}
+	return result
+}
@@ -0,0 +1,63 @@
+package dal
+
+import (
+	"hot-coffee/models"
+)
+
+// LoyaltyRepository defines the methods for reading and writing the loyalty rules and ledger.
+type LoyaltyRepository interface {
+	Locker
+	ReadRules() ([]models.LoyaltyRule, error)       // Reads all loyalty rules.
+	FindRule(id string) (models.LoyaltyRule, bool)  // Looks up one loyalty rule by ID.
+	WriteRules(rules []models.LoyaltyRule) error    // Writes the updated rules to the JSON file.
+	ReadLedger() ([]models.LoyaltyEntry, error)     // Reads all ledger entries, oldest first.
+	WriteLedger(ledger []models.LoyaltyEntry) error // Writes the updated ledger to the JSON file.
+	FindCustomer(id string) (models.Customer, bool) // Looks up the customer a balance belongs to.
+	FindMenuItem(id string) (models.MenuItem, bool) // Looks up a product named by a rule.
+}
+
+// jsonLoyaltyRepository implements the LoyaltyRepository interface using JSON file storage.
+type jsonLoyaltyRepository struct {
+	*FileLocks        // Locks shared with the other repositories.
+	store      *Store // In-memory copy of the data files.
+}
+
+// NewJSONLoyaltyRepository creates and returns a new instance of jsonLoyaltyRepository.
+func NewJSONLoyaltyRepository(store *Store, locks *FileLocks) LoyaltyRepository {
+	return &jsonLoyaltyRepository{FileLocks: locks, store: store}
+}
+
+// ReadRules returns the loyalty rules held in the in-memory store.
+func (r *jsonLoyaltyRepository) ReadRules() ([]models.LoyaltyRule, error) {
+	return r.store.LoyaltyRules(), nil
+}
+
+// FindRule returns the loyalty rule with the given ID and whether it exists.
+func (r *jsonLoyaltyRepository) FindRule(id string) (models.LoyaltyRule, bool) {
+	return r.store.LoyaltyRule(id)
+}
+
+// WriteRules writes the loyalty rules to the JSON file and the in-memory store.
+func (r *jsonLoyaltyRepository) WriteRules(rules []models.LoyaltyRule) error {
+	return r.store.SaveLoyaltyRules(rules)
+}
+
+// ReadLedger returns the loyalty ledger held in the in-memory store.
+func (r *jsonLoyaltyRepository) ReadLedger() ([]models.LoyaltyEntry, error) {
+	return r.store.Ledger(), nil
+}
+
+// WriteLedger writes the loyalty ledger to the JSON file and the in-memory store.
+func (r *jsonLoyaltyRepository) WriteLedger(ledger []models.LoyaltyEntry) error {
+	return r.store.SaveLedger(ledger)
+}
+
+// FindCustomer returns the customer with the given ID and whether it exists.
+func (r *jsonLoyaltyRepository) FindCustomer(id string) (models.Customer, bool) {
+	return r.store.Customer(id)
+}
+
+// FindMenuItem returns the menu item with the given ID and whether it exists.
+func (r *jsonLoyaltyRepository) FindMenuItem(id string) (models.MenuItem, bool) {
+	return r.store.MenuItem(id)
+}
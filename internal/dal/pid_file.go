@@ -0,0 +1,75 @@
+package dal
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"hot-coffee/internal/config"
+)
+
+// Name of the file in the data directory holding the ID of the process using it
+const pidFileName = "hot-coffee.pid"
+
+// ErrDataDirInUse is returned by LockDataDir while another process uses the data directory
+var ErrDataDirInUse = errors.New("The data directory is in use by a running server")
+
+// LockDataDir marks the data directory as used by this process with a pid file, so the
+// server and the commands that change data never run on it at the same time. A pid file
+// left by a process that is no longer running is taken over. The returned function
+// removes the pid file.
+func LockDataDir(cfg config.Config) (func(), error) {
+	if err := os.MkdirAll(cfg.DataDir, 0o755); err != nil {
+		return nil, err
+	}
+	path := filepath.Join(cfg.DataDir, pidFileName)
+	for {
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+		if err == nil {
+			_, err = fmt.Fprintf(file, "%d\n", os.Getpid())
+			if closeErr := file.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				os.Remove(path)
+				return nil, err
+			}
+			return func() { os.Remove(path) }, nil
+		}
+		if !os.IsExist(err) {
+			return nil, err
+		}
+		pid, running := pidFileOwner(path)
+		if running {
+			return nil, fmt.Errorf("%w (pid %d)", ErrDataDirInUse, pid)
+		}
+		slog.Warn("Removing stale pid file", slog.String("file", path), slog.Int("pid", pid))
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+}
+
+// Returns the process ID recorded in a pid file and whether that process is still running
+func pidFileOwner(path string) (int, bool) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, false
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil || pid <= 0 {
+		return 0, false
+	}
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return pid, false
+	}
+	// Signal 0 only checks that the process exists; a process of another user cannot be signalled but runs
+	err = process.Signal(syscall.Signal(0))
+	return pid, err == nil || errors.Is(err, os.ErrPermission)
+}
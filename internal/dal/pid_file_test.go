@@ -0,0 +1,50 @@
+package dal
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"hot-coffee/internal/config"
+)
+
+// A second process cannot lock the data directory until the first releases it
+func TestLockDataDir(t *testing.T) {
+	cfg := config.Default()
+	cfg.DataDir = t.TempDir()
+	unlock, err := LockDataDir(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LockDataDir(cfg); !errors.Is(err, ErrDataDirInUse) {
+		t.Fatalf("locking a locked data directory: %v, want ErrDataDirInUse", err)
+	}
+	unlock()
+	if got := readTestFile(t, filepath.Join(cfg.DataDir, pidFileName)); got != "<missing>" {
+		t.Errorf("pid file left behind: %s", got)
+	}
+	unlock, err = LockDataDir(cfg)
+	if err != nil {
+		t.Fatalf("locking a released data directory: %v", err)
+	}
+	unlock()
+}
+
+// A pid file naming no running process is taken over
+func TestLockDataDirStalePIDFile(t *testing.T) {
+	cfg := config.Default()
+	cfg.DataDir = t.TempDir()
+	for _, content := range []string{"2147483646\n", "", "garbage"} {
+		path := writeTestFile(t, cfg.DataDir, pidFileName, content)
+		unlock, err := LockDataDir(cfg)
+		if err != nil {
+			t.Fatalf("pid file %q: %v", content, err)
+		}
+		if got, want := readTestFile(t, path), strconv.Itoa(os.Getpid())+"\n"; got != want {
+			t.Errorf("pid file holds %q, want %q", got, want)
+		}
+		unlock()
+	}
+}
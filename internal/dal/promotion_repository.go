@@ -0,0 +1,95 @@
+package dal
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"hot-coffee/models"
+)
+
+// PromotionRepository defines the methods for reading and writing the promotions.
+type PromotionRepository interface {
+	Locker
+	ReadPromotions() ([]models.Promotion, error)         // Reads all promotions.
+	FindPromotion(id string) (models.Promotion, bool)    // Looks up one promotion by ID.
+	WritePromotions(promotions []models.Promotion) error // Writes the updated promotions to the JSON file.
+	FindMenuItem(id string) (models.MenuItem, bool)      // Looks up a product named by a promotion.
+}
+
+// jsonPromotionRepository implements the PromotionRepository interface using JSON file storage.
+type jsonPromotionRepository struct {
+	*FileLocks        // Locks shared with the other repositories.
+	store      *Store // In-memory copy of the data files.
+}
+
+// NewJSONPromotionRepository creates and returns a new instance of jsonPromotionRepository.
+func NewJSONPromotionRepository(store *Store, locks *FileLocks) PromotionRepository {
+	return &jsonPromotionRepository{FileLocks: locks, store: store}
+}
+
+// ReadPromotions returns the promotions held in the in-memory store.
+func (r *jsonPromotionRepository) ReadPromotions() ([]models.Promotion, error) {
+	return r.store.Promotions(), nil
+}
+
+// FindPromotion returns the promotion with the given ID and whether it exists.
+func (r *jsonPromotionRepository) FindPromotion(id string) (models.Promotion, bool) {
+	return r.store.Promotion(id)
+}
+
+// WritePromotions writes the promotions to the JSON file and the in-memory store.
+func (r *jsonPromotionRepository) WritePromotions(promotions []models.Promotion) error {
+	return r.store.SavePromotions(promotions)
+}
+
+// FindMenuItem returns the menu item with the given ID and whether it exists.
+func (r *jsonPromotionRepository) FindMenuItem(id string) (models.MenuItem, bool) {
+	return r.store.MenuItem(id)
+}
+
+// CheckPromotionRules checks that a promotion is of a known type and its discount can be
+// computed, e.g. that a buy_x_get_y promotion buys and gets at least one item
+func CheckPromotionRules(promotion models.Promotion) error {
+	switch promotion.Type {
+	case models.PromoPercentOff:
+		if promotion.Percent <= 0 || promotion.Percent > 100 {
+			return errors.New("Percent must be above 0 and at most 100")
+		}
+	case models.PromoFixedOff:
+		if promotion.Amount <= 0 {
+			return errors.New("Amount must be positive")
+		}
+	case models.PromoFixedPrice:
+		if promotion.Price < 0 {
+			return errors.New("Price cannot be negative")
+		}
+		if len(promotion.ProductIDs) == 0 {
+			return errors.New("Fixed price promotions need products")
+		}
+	case models.PromoBuyXGetY:
+		if promotion.BuyQuantity < 1 || promotion.GetQuantity < 1 {
+			return errors.New("Buy and get quantities must be at least 1")
+		}
+	default:
+		return fmt.Errorf("Unknown promotion type: %s", promotion.Type)
+	}
+	return nil
+}
+
+// Validates a promotions file: its IDs and the rules of every promotion
+func validatePromotions(data []byte) error {
+	if err := validateIDs(func(p models.Promotion) string { return p.ID })(data); err != nil {
+		return err
+	}
+	var promotions []models.Promotion
+	if err := json.Unmarshal(data, &promotions); err != nil {
+		return err
+	}
+	for _, promotion := range promotions {
+		if err := CheckPromotionRules(promotion); err != nil {
+			return fmt.Errorf("promotion %q: %v", promotion.ID, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package dal
+
+import (
+	"os"
+
+	"hot-coffee/internal/config"
+)
+
+// SequenceRepository persists the last order number issued by the sequence ID strategy
+type SequenceRepository interface {
+	ReadSequence() (int, error)
+	WriteSequence(last int) error
+}
+
+type jsonSequenceRepository struct {
+	cfg config.Config
+}
+
+// Sequence file contents
+type sequenceFile struct {
+	LastID int `json:"last_id"`
+}
+
+// Creates and returns a new instance of jsonSequenceRepository
+func NewJSONSequenceRepository(cfg config.Config) SequenceRepository {
+	return &jsonSequenceRepository{cfg: cfg}
+}
+
+// Reads the last issued order number, returning 0 if no sequence file exists yet
+func (r *jsonSequenceRepository) ReadSequence() (int, error) {
+	var seq sequenceFile
+	err := readJSONFile(r.cfg.SequencePath(), &seq)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return seq.LastID, nil
+}
+
+// Atomically stores the last issued order number
+func (r *jsonSequenceRepository) WriteSequence(last int) error {
+	return WriteJSONAtomic(r.cfg.SequencePath(), sequenceFile{LastID: last})
+}
@@ -0,0 +1,284 @@
+package dal
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+
+	"hot-coffee/internal/config"
+	"hot-coffee/models"
+)
+
+// Store keeps the data files listed in storeFiles in memory, indexed by ID. It is loaded
+// once at startup; reads are served from memory and every write goes to disk first
+// (write-through) and only replaces the cached data once the file is safely written.
+// Changes made to the data files by other programs while the server runs are not seen.
+//
+// All getters return copies, so callers may modify the result freely.
+type Store struct {
+	cfg     config.Config
+	writeMu sync.Mutex   // Serializes disk writes together with the cache swap that follows them.
+	mu      sync.RWMutex // Guards the cached data below.
+
+	version uint64           // Incremented on every commit.
+	tables  map[string]table // Cached entries of each file in storeFiles, by its name.
+}
+
+// LoadStore reads the data files named by cfg into a new Store
+func LoadStore(cfg config.Config) (*Store, error) {
+	s := &Store{cfg: cfg, tables: make(map[string]table, len(storeFiles))}
+	counts := make([]any, 0, len(storeFiles))
+	for _, file := range storeFiles {
+		data, err := os.ReadFile(file.path(cfg))
+		if err != nil {
+			return nil, err
+		}
+		entries, err := file.decode(data)
+		if err != nil {
+			return nil, fmt.Errorf("Data file %s is corrupt: %w", file.path(cfg), err)
+		}
+		s.tables[file.name()] = file.newTable(entries)
+		counts = append(counts, slog.Int(file.name(), file.length(entries)))
+	}
+	slog.Info("Data loaded", counts...)
+	return s, nil
+}
+
+// Decodes the JSON file at path into v; an empty file leaves v untouched
+func readJSONFile(path string, v any) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if len(data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(data, v)
+}
+
+// Orders returns a copy of all orders
+func (s *Store) Orders() []models.Order {
+	return allEntries(s, ordersData)
+}
+
+// Order returns a copy of the order with the given ID
+func (s *Store) Order(id string) (models.Order, bool) {
+	return findEntry(s, ordersData, id)
+}
+
+// Menu returns a copy of all menu items
+func (s *Store) Menu() []models.MenuItem {
+	return allEntries(s, menuData)
+}
+
+// MenuItem returns a copy of the menu item with the given ID
+func (s *Store) MenuItem(id string) (models.MenuItem, bool) {
+	return findEntry(s, menuData, id)
+}
+
+// Inventory returns a copy of all inventory items
+func (s *Store) Inventory() []models.InventoryItem {
+	return allEntries(s, inventoryData)
+}
+
+// InventoryItem returns the inventory item with the given ingredient ID
+func (s *Store) InventoryItem(id string) (models.InventoryItem, bool) {
+	return findEntry(s, inventoryData, id)
+}
+
+// Customers returns a copy of all customers
+func (s *Store) Customers() []models.Customer {
+	return allEntries(s, customersData)
+}
+
+// Customer returns the customer with the given ID
+func (s *Store) Customer(id string) (models.Customer, bool) {
+	return findEntry(s, customersData, id)
+}
+
+// LoyaltyRules returns a copy of all loyalty rules
+func (s *Store) LoyaltyRules() []models.LoyaltyRule {
+	return allEntries(s, loyaltyRulesData)
+}
+
+// LoyaltyRule returns a copy of the loyalty rule with the given ID
+func (s *Store) LoyaltyRule(id string) (models.LoyaltyRule, bool) {
+	return findEntry(s, loyaltyRulesData, id)
+}
+
+// Ledger returns a copy of all loyalty ledger entries, oldest first
+func (s *Store) Ledger() []models.LoyaltyEntry {
+	return allEntries(s, ledgerData)
+}
+
+// Promotions returns a copy of all promotions
+func (s *Store) Promotions() []models.Promotion {
+	return allEntries(s, promotionsData)
+}
+
+// Promotion returns a copy of the promotion with the given ID
+func (s *Store) Promotion(id string) (models.Promotion, bool) {
+	return findEntry(s, promotionsData, id)
+}
+
+// TaxRates returns a copy of all tax rates
+func (s *Store) TaxRates() []models.TaxRate {
+	return allEntries(s, taxRatesData)
+}
+
+// TaxRate returns the tax rate with the given ID
+func (s *Store) TaxRate(id string) (models.TaxRate, bool) {
+	return findEntry(s, taxRatesData, id)
+}
+
+// Returns the cached entries of every file in storeFiles, by name, as one commit left them.
+// Commits replace the entries rather than change them, so they are shared and must not be modified.
+func (s *Store) contents() map[string]any {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	contents := make(map[string]any, len(s.tables))
+	for name, cached := range s.tables {
+		contents[name] = cached.entries
+	}
+	return contents
+}
+
+// Version returns a number that changes whenever a commit changes the data
+func (s *Store) Version() uint64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.version
+}
+
+// SaveOrders writes the orders to disk and then replaces the cached orders
+func (s *Store) SaveOrders(orders []models.Order) error {
+	return saveEntries(s, ordersData, orders)
+}
+
+// SaveMenu writes the menu to disk and then replaces the cached menu
+func (s *Store) SaveMenu(menu []models.MenuItem) error {
+	return saveEntries(s, menuData, menu)
+}
+
+// SaveInventory writes the inventory to disk and then replaces the cached inventory
+func (s *Store) SaveInventory(inventory []models.InventoryItem) error {
+	return saveEntries(s, inventoryData, inventory)
+}
+
+// SaveCustomers writes the customers to disk and then replaces the cached customers
+func (s *Store) SaveCustomers(customers []models.Customer) error {
+	return saveEntries(s, customersData, customers)
+}
+
+// SaveLoyaltyRules writes the loyalty rules to disk and then replaces the cached rules
+func (s *Store) SaveLoyaltyRules(rules []models.LoyaltyRule) error {
+	return saveEntries(s, loyaltyRulesData, rules)
+}
+
+// SaveLedger writes the loyalty ledger to disk and then replaces the cached ledger
+func (s *Store) SaveLedger(ledger []models.LoyaltyEntry) error {
+	return saveEntries(s, ledgerData, ledger)
+}
+
+// SavePromotions writes the promotions to disk and then replaces the cached promotions
+func (s *Store) SavePromotions(promotions []models.Promotion) error {
+	return saveEntries(s, promotionsData, promotions)
+}
+
+// SaveTaxRates writes the tax rates to disk and then replaces the cached tax rates
+func (s *Store) SaveTaxRates(taxRates []models.TaxRate) error {
+	return saveEntries(s, taxRatesData, taxRates)
+}
+
+// StoreTx stages changes to several of the store's files; Commit writes them to disk in
+// a single transaction and only then updates the cache, so memory never runs ahead of disk.
+type StoreTx struct {
+	store  *Store
+	tx     *Tx
+	staged map[string]any // Entries staged for each file in storeFiles, by its name.
+}
+
+// Begin starts a transaction over the store's files
+func (s *Store) Begin() *StoreTx {
+	return &StoreTx{store: s, tx: NewTx(s.cfg.DataDir), staged: make(map[string]any)}
+}
+
+// StageOrders stages the full list of orders to replace the orders file
+func (t *StoreTx) StageOrders(orders []models.Order) error {
+	return stageEntries(t, ordersData, orders)
+}
+
+// StageMenu stages the full list of menu items to replace the menu file
+func (t *StoreTx) StageMenu(menu []models.MenuItem) error {
+	return stageEntries(t, menuData, menu)
+}
+
+// StageInventory stages the full list of inventory items to replace the inventory file
+func (t *StoreTx) StageInventory(inventory []models.InventoryItem) error {
+	return stageEntries(t, inventoryData, inventory)
+}
+
+// StageCustomers stages the full list of customers to replace the customers file
+func (t *StoreTx) StageCustomers(customers []models.Customer) error {
+	return stageEntries(t, customersData, customers)
+}
+
+// StageLoyaltyRules stages the full list of loyalty rules to replace the loyalty rules file
+func (t *StoreTx) StageLoyaltyRules(rules []models.LoyaltyRule) error {
+	return stageEntries(t, loyaltyRulesData, rules)
+}
+
+// StageLedger stages the full list of loyalty entries to replace the loyalty ledger file
+func (t *StoreTx) StageLedger(ledger []models.LoyaltyEntry) error {
+	return stageEntries(t, ledgerData, ledger)
+}
+
+// StagePromotions stages the full list of promotions to replace the promotions file
+func (t *StoreTx) StagePromotions(promotions []models.Promotion) error {
+	return stageEntries(t, promotionsData, promotions)
+}
+
+// StageTaxRates stages the full list of tax rates to replace the tax rates file
+func (t *StoreTx) StageTaxRates(taxRates []models.TaxRate) error {
+	return stageEntries(t, taxRatesData, taxRates)
+}
+
+// Stages a file that is not cached by the store to be replaced in the same transaction
+func (t *StoreTx) stageFile(path string, v any) error {
+	return t.tx.StageJSON(path, v)
+}
+
+// Commit writes the staged files and updates the cache. A transaction whose journal is on
+// disk has committed even if some files are not in place yet: the next commit or start rolls
+// it forward, so it is applied to the cache and reported as successful.
+func (t *StoreTx) Commit() error {
+	s := t.store
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	err := t.tx.Commit()
+	if err != nil && !errors.Is(err, ErrTxIncomplete) {
+		return err
+	}
+	if err != nil {
+		slog.Warn(err.Error())
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, file := range storeFiles {
+		if entries, staged := t.staged[file.name()]; staged {
+			s.tables[file.name()] = file.newTable(entries)
+		}
+	}
+	s.version++
+	return nil
+}
+
+// Rollback discards the staged changes; it is a no-op after Commit
+func (t *StoreTx) Rollback() {
+	t.tx.Rollback()
+}
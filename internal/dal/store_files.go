@@ -0,0 +1,253 @@
+package dal
+
+import (
+	"encoding/json"
+	"maps"
+	"path/filepath"
+	"slices"
+
+	"hot-coffee/internal/config"
+	"hot-coffee/models"
+)
+
+// The data files cached by the Store. Loading, bootstrap, snapshots, restores and commits
+// all go over this list, so a new entity is added here and nowhere else in the data layer.
+var storeFiles = []cachedFile{
+	ordersData, menuData, inventoryData,
+	customersData, loyaltyRulesData, ledgerData, promotionsData, taxRatesData,
+}
+
+var (
+	ordersData = &entityFile[models.Order]{
+		key: "orders", fileName: func(cfg config.Config) string { return cfg.OrdersFile },
+		idOf: func(o models.Order) string { return o.ID }, clone: cloneOrder, seed: latestSeedOrders,
+	}
+	menuData = &entityFile[models.MenuItem]{
+		key: "menu_items", fileName: func(cfg config.Config) string { return cfg.MenuFile },
+		idOf: func(m models.MenuItem) string { return m.ID }, clone: cloneMenuItem,
+		seed: func(cfg config.Config) func() (string, error) { return seedFile(cfg.SeedMenuPath()) },
+	}
+	inventoryData = &entityFile[models.InventoryItem]{
+		key: "inventory_items", fileName: func(cfg config.Config) string { return cfg.InventoryFile },
+		idOf: func(i models.InventoryItem) string { return i.IngredientID },
+		seed: func(cfg config.Config) func() (string, error) { return seedFile(cfg.SeedInventoryPath()) },
+	}
+	// The files below were added after the first snapshots were taken and have no seed copy
+	customersData = &entityFile[models.Customer]{
+		key: "customers", fileName: func(cfg config.Config) string { return cfg.CustomersFile },
+		idOf: func(c models.Customer) string { return c.ID }, optional: true,
+	}
+	loyaltyRulesData = &entityFile[models.LoyaltyRule]{
+		key: "loyalty_rules", fileName: func(cfg config.Config) string { return cfg.LoyaltyRulesFile },
+		idOf: func(rule models.LoyaltyRule) string { return rule.ID }, clone: cloneLoyaltyRule, optional: true,
+	}
+	ledgerData = &entityFile[models.LoyaltyEntry]{
+		key: "loyalty_entries", fileName: func(cfg config.Config) string { return cfg.LoyaltyLedgerFile },
+		idOf: func(e models.LoyaltyEntry) string { return e.ID }, optional: true,
+	}
+	promotionsData = &entityFile[models.Promotion]{
+		key: "promotions", fileName: func(cfg config.Config) string { return cfg.PromotionsFile },
+		idOf: func(p models.Promotion) string { return p.ID }, clone: clonePromotion,
+		validate: validatePromotions, optional: true,
+	}
+	taxRatesData = &entityFile[models.TaxRate]{
+		key: "tax_rates", fileName: func(cfg config.Config) string { return cfg.TaxRatesFile },
+		idOf: func(rate models.TaxRate) string { return rate.ID }, optional: true,
+	}
+)
+
+// A data file cached by the Store, with the entry type hidden so that files of different
+// types can be listed together
+type cachedFile interface {
+	name() string                        // Names the entries in logs and snapshot manifests.
+	path(cfg config.Config) string       // Full path of the live file.
+	baseName(cfg config.Config) string   // Name of the file inside a snapshot.
+	dataFile(cfg config.Config) dataFile // How Bootstrap creates, seeds and validates the file.
+	missingFromOldSnapshots() bool       // Snapshots taken before the file existed lack it.
+	decode(data []byte) (any, error)     // Validates the content of the file and returns its entries.
+	newTable(entries any) table          // Copies the entries into a table indexed by ID.
+	length(entries any) int              // Counts the entries.
+	stage(t *StoreTx, entries any) error // Stages the entries to replace the file.
+}
+
+// The cached entries of one data file, indexed by ID
+type table struct {
+	entries any            // A slice of the file's entry type.
+	index   map[string]int // Position of each entry by ID.
+}
+
+// A data file holding a JSON list of T
+type entityFile[T any] struct {
+	key      string
+	fileName func(cfg config.Config) string
+	idOf     func(T) string
+	clone    func(T) T                                      // Deep copy of one entry; nil when T holds no slices or maps.
+	validate func(data []byte) error                        // Checks the file content; nil checks the IDs only.
+	seed     func(cfg config.Config) func() (string, error) // Seed source; nil when there is no seed copy.
+	optional bool                                           // Reported by missingFromOldSnapshots.
+}
+
+func (f *entityFile[T]) name() string {
+	return f.key
+}
+
+func (f *entityFile[T]) path(cfg config.Config) string {
+	return cfg.DataPath(f.fileName(cfg))
+}
+
+func (f *entityFile[T]) baseName(cfg config.Config) string {
+	return filepath.Base(f.fileName(cfg))
+}
+
+func (f *entityFile[T]) dataFile(cfg config.Config) dataFile {
+	seedFrom := noSeed
+	if f.seed != nil {
+		seedFrom = f.seed(cfg)
+	}
+	return dataFile{path: f.path(cfg), validate: f.check, seedFrom: seedFrom}
+}
+
+func (f *entityFile[T]) missingFromOldSnapshots() bool {
+	return f.optional
+}
+
+func (f *entityFile[T]) decode(data []byte) (any, error) {
+	entries := []T{}
+	if len(data) == 0 {
+		return entries, nil
+	}
+	if err := f.check(data); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+func (f *entityFile[T]) newTable(entries any) table {
+	list := f.copy(entries.([]T))
+	index := make(map[string]int, len(list))
+	for i, entry := range list {
+		index[f.idOf(entry)] = i
+	}
+	return table{entries: list, index: index}
+}
+
+func (f *entityFile[T]) length(entries any) int {
+	return len(entries.([]T))
+}
+
+func (f *entityFile[T]) stage(t *StoreTx, entries any) error {
+	return stageEntries(t, f, entries.([]T))
+}
+
+// Validates the content of the file with the custom validator or by its IDs
+func (f *entityFile[T]) check(data []byte) error {
+	if f.validate != nil {
+		return f.validate(data)
+	}
+	return validateIDs(f.idOf)(data)
+}
+
+// Returns a deep copy of the entries
+func (f *entityFile[T]) copy(entries []T) []T {
+	if f.clone == nil {
+		return slices.Clone(entries)
+	}
+	result := make([]T, len(entries))
+	for i, entry := range entries {
+		result[i] = f.clone(entry)
+	}
+	return result
+}
+
+// Returns a copy of all cached entries of a file
+func allEntries[T any](s *Store, f *entityFile[T]) []T {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return f.copy(s.tables[f.key].entries.([]T))
+}
+
+// Returns a copy of the cached entry with the given ID
+func findEntry[T any](s *Store, f *entityFile[T], id string) (T, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	cached := s.tables[f.key]
+	i, exists := cached.index[id]
+	if !exists {
+		var zero T
+		return zero, false
+	}
+	entry := cached.entries.([]T)[i]
+	if f.clone != nil {
+		entry = f.clone(entry)
+	}
+	return entry, true
+}
+
+// Writes the entries of a file to disk and then replaces the cached ones
+func saveEntries[T any](s *Store, f *entityFile[T], entries []T) error {
+	tx := s.Begin()
+	if err := stageEntries(tx, f, entries); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Stages the full list of entries to replace a file
+func stageEntries[T any](t *StoreTx, f *entityFile[T], entries []T) error {
+	if entries == nil {
+		entries = []T{}
+	}
+	t.staged[f.key] = entries
+	return t.tx.StageJSON(f.path(t.store.cfg), entries)
+}
+
+// Returns a deep copy of one order
+func cloneOrder(order models.Order) models.Order {
+	order.Items = slices.Clone(order.Items)
+	for i := range order.Items {
+		order.Items[i].Modifiers = slices.Clone(order.Items[i].Modifiers)
+	}
+	order.StatusHistory = slices.Clone(order.StatusHistory)
+	order.Reserved = maps.Clone(order.Reserved)
+	order.Consumed = maps.Clone(order.Consumed)
+	order.PromoCodes = slices.Clone(order.PromoCodes)
+	order.Discounts = slices.Clone(order.Discounts)
+	order.Taxes = slices.Clone(order.Taxes)
+	order.Payments = slices.Clone(order.Payments)
+	return order
+}
+
+// Returns a deep copy of one menu item
+func cloneMenuItem(item models.MenuItem) models.MenuItem {
+	item.Ingredients = slices.Clone(item.Ingredients)
+	item.Variants = slices.Clone(item.Variants)
+	for i := range item.Variants {
+		item.Variants[i].Ingredients = slices.Clone(item.Variants[i].Ingredients)
+	}
+	item.ModifierGroups = slices.Clone(item.ModifierGroups)
+	for i, group := range item.ModifierGroups {
+		item.ModifierGroups[i].Options = slices.Clone(group.Options)
+		for j, option := range item.ModifierGroups[i].Options {
+			item.ModifierGroups[i].Options[j].Changes = slices.Clone(option.Changes)
+		}
+	}
+	return item
+}
+
+// Returns a deep copy of one loyalty rule
+func cloneLoyaltyRule(rule models.LoyaltyRule) models.LoyaltyRule {
+	rule.ProductIDs = slices.Clone(rule.ProductIDs)
+	rule.RewardProductIDs = slices.Clone(rule.RewardProductIDs)
+	return rule
+}
+
+// Returns a deep copy of one promotion
+func clonePromotion(promotion models.Promotion) models.Promotion {
+	promotion.ProductIDs = slices.Clone(promotion.ProductIDs)
+	promotion.RequiresProductIDs = slices.Clone(promotion.RequiresProductIDs)
+	promotion.Days = slices.Clone(promotion.Days)
+	return promotion
+}
@@ -0,0 +1,90 @@
+package dal
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"hot-coffee/internal/config"
+	"hot-coffee/models"
+)
+
+// Returns a store over a fresh data directory with empty data files
+func newTestStore(t *testing.T) (*Store, config.Config) {
+	t.Helper()
+	dir := t.TempDir()
+	cfg := config.Default()
+	cfg.DataDir, cfg.SeedDir, cfg.BackupDir = dir, dir, filepath.Join(dir, "backups")
+	if err := Bootstrap(cfg); err != nil {
+		t.Fatal(err)
+	}
+	store, err := LoadStore(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store, cfg
+}
+
+// A commit whose journal is on disk but whose renames fail has committed: it is reported as
+// successful, served from memory, and in place on disk after the next commit
+func TestStoreCommitWithFailedRename(t *testing.T) {
+	store, cfg := newTestStore(t)
+	rename = func(string, string) error { return errors.New("Injected rename failure") }
+	t.Cleanup(func() { rename = os.Rename })
+
+	tx := store.Begin()
+	if err := tx.StageOrders([]models.Order{{ID: "1", CustomerName: "Ada", Status: models.StatusOpen}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("commit with a journal on disk failed: %v", err)
+	}
+	if _, exists := store.Order("1"); !exists {
+		t.Error("committed order is not served")
+	}
+	if _, err := os.Stat(filepath.Join(cfg.DataDir, txJournalFile)); err != nil {
+		t.Fatalf("journal of the incomplete commit: %v", err)
+	}
+
+	rename = os.Rename
+	tx = store.Begin()
+	if err := tx.StageInventory([]models.InventoryItem{{IngredientID: "milk", Name: "Milk", Quantity: 1, Unit: "l"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	reloaded, err := LoadStore(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, exists := reloaded.Order("1"); !exists {
+		t.Error("the next commit did not complete the incomplete one")
+	}
+	if len(reloaded.Inventory()) != 1 {
+		t.Errorf("inventory on disk is %+v, want the next commit's", reloaded.Inventory())
+	}
+}
+
+// A commit that fails before its journal is written changes neither the disk nor the cache
+func TestStoreCommitFailureKeepsCache(t *testing.T) {
+	store, cfg := newTestStore(t)
+	tx := store.Begin()
+	if err := tx.StageOrders([]models.Order{{ID: "1", CustomerName: "Ada", Status: models.StatusOpen}}); err != nil {
+		t.Fatal(err)
+	}
+	// A directory in the journal's place makes writing the journal fail
+	if err := os.Mkdir(filepath.Join(cfg.DataDir, txJournalFile), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err == nil {
+		t.Fatal("commit without a journal succeeded")
+	}
+	if _, exists := store.Order("1"); exists {
+		t.Error("failed commit is served")
+	}
+	if temps := tempFiles(t, cfg.DataDir); len(temps) != 0 {
+		t.Errorf("temp files left behind: %v", temps)
+	}
+}
@@ -0,0 +1,39 @@
+package dal
+
+import (
+	"hot-coffee/models"
+)
+
+// TaxRepository defines the methods for reading and writing the tax rates.
+type TaxRepository interface {
+	Locker
+	ReadRates() ([]models.TaxRate, error)      // Reads all tax rates.
+	FindRate(id string) (models.TaxRate, bool) // Looks up one tax rate by ID.
+	WriteRates(rates []models.TaxRate) error   // Writes the updated tax rates to the JSON file.
+}
+
+// jsonTaxRepository implements the TaxRepository interface using JSON file storage.
+type jsonTaxRepository struct {
+	*FileLocks        // Locks shared with the other repositories.
+	store      *Store // In-memory copy of the data files.
+}
+
+// NewJSONTaxRepository creates and returns a new instance of jsonTaxRepository.
+func NewJSONTaxRepository(store *Store, locks *FileLocks) TaxRepository {
+	return &jsonTaxRepository{FileLocks: locks, store: store}
+}
+
+// ReadRates returns the tax rates held in the in-memory store.
+func (r *jsonTaxRepository) ReadRates() ([]models.TaxRate, error) {
+	return r.store.TaxRates(), nil
+}
+
+// FindRate returns the tax rate with the given ID and whether it exists.
+func (r *jsonTaxRepository) FindRate(id string) (models.TaxRate, bool) {
+	return r.store.TaxRate(id)
+}
+
+// WriteRates writes the tax rates to the JSON file and the in-memory store.
+func (r *jsonTaxRepository) WriteRates(rates []models.TaxRate) error {
+	return r.store.SaveTaxRates(rates)
+}
@@ -0,0 +1,226 @@
+package dal
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+)
+
+// Name of the journal that marks a transaction as committed until all its files are in place
+const txJournalFile = "tx-journal.json"
+
+// Tx groups writes to several data files so that they are applied together or not at all.
+//
+// Commit first writes every staged file to a synced temp file, then records the
+// temp/target pairs in a journal. Once the journal is on disk the transaction is
+// committed: the temp files are renamed over their targets and the journal is removed.
+// If the process dies after the journal is written, RecoverTempFiles rolls the
+// transaction forward on the next start; if it dies before, the temp files are discarded.
+type Tx struct {
+	dir    string
+	writes []stagedWrite
+	done   bool
+}
+
+type stagedWrite struct {
+	target string
+	data   []byte
+}
+
+// Renames a file into place; replaced in tests to make a rename fail
+var rename = os.Rename
+
+// ErrTxIncomplete is returned by Commit when the transaction is durably committed but some
+// of its files could not be moved into place yet; they are completed by the next commit or start.
+var ErrTxIncomplete = errors.New("Transaction committed but not fully applied, it will be completed by the next write or restart")
+
+// One temp/target pair recorded in the journal
+type journalEntry struct {
+	Temp   string `json:"temp"`
+	Target string `json:"target"`
+}
+
+// NewTx starts a transaction whose journal is kept in dir
+func NewTx(dir string) *Tx {
+	return &Tx{dir: dir}
+}
+
+// StageJSON encodes v as indented JSON and stages it to replace the file at path on commit.
+// Staging the same path twice keeps only the last value.
+func (tx *Tx) StageJSON(path string, v any) error {
+	if tx.done {
+		return errors.New("Transaction already finished")
+	}
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+	for i := range tx.writes {
+		if tx.writes[i].target == path {
+			tx.writes[i].data = data
+			return nil
+		}
+	}
+	tx.writes = append(tx.writes, stagedWrite{target: path, data: data})
+	return nil
+}
+
+// Rollback discards all staged writes; it is a no-op after Commit
+func (tx *Tx) Rollback() {
+	tx.done = true
+	tx.writes = nil
+}
+
+// Commit applies all staged writes. On error before the journal is written nothing
+// has changed on disk; on error after that the remaining renames are retried by the next
+// commit or on startup, and ErrTxIncomplete is returned.
+func (tx *Tx) Commit() error {
+	if tx.done {
+		return errors.New("Transaction already finished")
+	}
+	tx.done = true
+	if len(tx.writes) == 0 {
+		return nil
+	}
+	// A journal left by an earlier commit is rolled forward first, so its files are in place
+	// before this transaction replaces them and its journal is never overwritten
+	if err := recoverTransaction(tx.dir); err != nil {
+		return fmt.Errorf("Previous transaction could not be completed: %w", err)
+	}
+
+	entries := make([]journalEntry, 0, len(tx.writes))
+	removeTemps := func() {
+		for _, entry := range entries {
+			os.Remove(entry.Temp)
+		}
+	}
+	for _, write := range tx.writes {
+		tmpName, err := writeTemp(write.target, write.data)
+		if err != nil {
+			removeTemps()
+			return err
+		}
+		entries = append(entries, journalEntry{Temp: tmpName, Target: write.target})
+	}
+
+	if err := tx.writeJournal(entries); err != nil {
+		removeTemps()
+		return err
+	}
+	if err := applyJournal(tx.dir, entries); err != nil {
+		return fmt.Errorf("%w: %v", ErrTxIncomplete, err)
+	}
+	return nil
+}
+
+// Returns the path of the journal file for this transaction
+func (tx *Tx) journalPath() string {
+	return filepath.Join(tx.dir, txJournalFile)
+}
+
+// Writes the journal directly (not via a temp file) so that a torn write leaves invalid JSON.
+// It never replaces an existing journal, and removes its own journal if it fails.
+func (tx *Tx) writeJournal(entries []journalEntry) error {
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return err
+	}
+	journal, err := os.OpenFile(tx.journalPath(), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := journal.Write(data); err != nil {
+		journal.Close()
+		os.Remove(tx.journalPath())
+		return err
+	}
+	if err := journal.Sync(); err != nil {
+		journal.Close()
+		os.Remove(tx.journalPath())
+		return err
+	}
+	if err := journal.Close(); err != nil {
+		os.Remove(tx.journalPath())
+		return err
+	}
+	return syncDir(tx.dir)
+}
+
+// Writes data to a synced temp file next to target and returns its name
+func writeTemp(target string, data []byte) (string, error) {
+	tmp, err := os.CreateTemp(filepath.Dir(target), "."+filepath.Base(target)+tempFileMarker+"*")
+	if err != nil {
+		return "", err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	return tmp.Name(), nil
+}
+
+// Renames every journaled temp file that is still present over its target, then removes the journal.
+// A missing temp file means its rename already happened.
+func applyJournal(dir string, entries []journalEntry) error {
+	touched := map[string]bool{}
+	for _, entry := range entries {
+		if _, err := os.Stat(entry.Temp); os.IsNotExist(err) {
+			continue
+		}
+		if err := rename(entry.Temp, entry.Target); err != nil {
+			return err
+		}
+		touched[filepath.Dir(entry.Target)] = true
+	}
+	for d := range touched {
+		if err := syncDir(d); err != nil {
+			return err
+		}
+	}
+	if err := os.Remove(filepath.Join(dir, txJournalFile)); err != nil {
+		return err
+	}
+	return syncDir(dir)
+}
+
+// Completes a transaction interrupted after its journal was written. A journal that
+// cannot be decoded was torn mid-write, so its transaction never committed and it is
+// removed; the orphaned temp files are then cleaned up by RecoverTempFiles.
+func recoverTransaction(dir string) error {
+	journalPath := filepath.Join(dir, txJournalFile)
+	data, err := os.ReadFile(journalPath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var entries []journalEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		slog.Warn("Discarding incomplete transaction journal", slog.String("file", journalPath))
+		return os.Remove(journalPath)
+	}
+	if err := applyJournal(dir, entries); err != nil {
+		return err
+	}
+	slog.Warn("Completed interrupted transaction", slog.Int("files", len(entries)))
+	return nil
+}
@@ -0,0 +1,148 @@
+package dal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Steps of a commit after which the process can die
+const (
+	crashAfterTemps   = iota // The temp files are written, the journal is not.
+	crashAfterJournal        // The journal is written, no file is renamed yet.
+	crashMidApply            // The journal is written and the first file is renamed.
+)
+
+// Runs a commit of new content over orders.json and inventory.json in dir up to the given
+// step, as if the process died there, and returns the paths of the two files
+func crashCommit(t *testing.T, dir string, step int) (string, string) {
+	t.Helper()
+	orders := writeTestFile(t, dir, "orders.json", `["old orders"]`)
+	inventory := writeTestFile(t, dir, "inventory.json", `["old inventory"]`)
+	tx := NewTx(dir)
+	var entries []journalEntry
+	for target, data := range map[string]string{orders: `["new orders"]`, inventory: `["new inventory"]`} {
+		tmpName, err := writeTemp(target, []byte(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		entries = append(entries, journalEntry{Temp: tmpName, Target: target})
+	}
+	if step == crashAfterTemps {
+		return orders, inventory
+	}
+	if err := tx.writeJournal(entries); err != nil {
+		t.Fatal(err)
+	}
+	if step == crashMidApply {
+		if err := os.Rename(entries[0].Temp, entries[0].Target); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return orders, inventory
+}
+
+// Recovery after a crash at any step leaves either both old files or both new files
+func TestRecoverInterruptedCommit(t *testing.T) {
+	tests := []struct {
+		name string
+		step int
+		want string // "old" or "new"
+	}{
+		{"before the journal", crashAfterTemps, "old"},
+		{"after the journal", crashAfterJournal, "new"},
+		{"midway through the renames", crashMidApply, "new"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			orders, inventory := crashCommit(t, dir, test.step)
+			if err := RecoverTempFiles(dir); err != nil {
+				t.Fatal(err)
+			}
+			if got := readTestFile(t, orders); got != `["`+test.want+` orders"]` {
+				t.Errorf("orders holds %s, want the %s content", got, test.want)
+			}
+			if got := readTestFile(t, inventory); got != `["`+test.want+` inventory"]` {
+				t.Errorf("inventory holds %s, want the %s content", got, test.want)
+			}
+			if got := readTestFile(t, filepath.Join(dir, txJournalFile)); got != "<missing>" {
+				t.Errorf("journal left behind: %s", got)
+			}
+			if temps := tempFiles(t, dir); len(temps) != 0 {
+				t.Errorf("temp files left behind: %v", temps)
+			}
+		})
+	}
+}
+
+// A journal torn mid-write never committed its transaction, so recovery discards it with the
+// temp files it names
+func TestRecoverTornJournal(t *testing.T) {
+	dir := t.TempDir()
+	orders, inventory := crashCommit(t, dir, crashAfterTemps)
+	writeTestFile(t, dir, txJournalFile, `[{"temp":"`)
+	if err := RecoverTempFiles(dir); err != nil {
+		t.Fatal(err)
+	}
+	if got := readTestFile(t, orders); got != `["old orders"]` {
+		t.Errorf("orders holds %s, want the old content", got)
+	}
+	if got := readTestFile(t, inventory); got != `["old inventory"]` {
+		t.Errorf("inventory holds %s, want the old content", got)
+	}
+	if got := readTestFile(t, filepath.Join(dir, txJournalFile)); got != "<missing>" {
+		t.Errorf("torn journal left behind: %s", got)
+	}
+	if temps := tempFiles(t, dir); len(temps) != 0 {
+		t.Errorf("temp files left behind: %v", temps)
+	}
+}
+
+// A new journal never replaces one that is still on disk
+func TestWriteJournalKeepsExistingJournal(t *testing.T) {
+	dir := t.TempDir()
+	journal := writeTestFile(t, dir, txJournalFile, `[]`)
+	tx := NewTx(dir)
+	if err := tx.writeJournal([]journalEntry{{Temp: "a", Target: "b"}}); err == nil {
+		t.Fatal("writing a journal over an existing one succeeded")
+	}
+	if got := readTestFile(t, journal); got != `[]` {
+		t.Errorf("existing journal replaced with %s", got)
+	}
+}
+
+// A commit rolls forward the journal an interrupted commit left before applying its own writes
+func TestCommitCompletesLeftoverJournal(t *testing.T) {
+	dir := t.TempDir()
+	orders, inventory := crashCommit(t, dir, crashAfterJournal)
+	tx := NewTx(dir)
+	if err := tx.StageJSON(orders, []string{"newest orders"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if got := readTestFile(t, orders); got != "[\n  \"newest orders\"\n]\n" {
+		t.Errorf("orders holds %s, want the last commit's content", got)
+	}
+	if got := readTestFile(t, inventory); got != `["new inventory"]` {
+		t.Errorf("inventory holds %s, want the interrupted commit's content", got)
+	}
+	if temps := tempFiles(t, dir); len(temps) != 0 {
+		t.Errorf("temp files left behind: %v", temps)
+	}
+}
+
+// Nothing can be staged or committed once a transaction is finished
+func TestTxFinished(t *testing.T) {
+	dir := t.TempDir()
+	tx := NewTx(dir)
+	tx.Rollback()
+	if err := tx.StageJSON(filepath.Join(dir, "orders.json"), []string{}); err == nil {
+		t.Error("staging after a rollback succeeded")
+	}
+	if err := tx.Commit(); err == nil {
+		t.Error("committing after a rollback succeeded")
+	}
+}
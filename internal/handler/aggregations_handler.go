@@ -5,12 +5,12 @@ import (
 	"net/http"
 
 	"hot-coffee/internal/service"
-	"hot-coffee/models"
 )
 
 type AggregationsHandler interface {
 	PopularItems(w http.ResponseWriter, r *http.Request)
 	TotalSales(w http.ResponseWriter, r *http.Request)
+	TaxReport(w http.ResponseWriter, r *http.Request)
 }
 
 type aggregationsHandler struct {
@@ -21,15 +21,27 @@ func NewAggregationsHandler(aggregationsService service.AggregationsService) Agg
 	return &aggregationsHandler{aggregationsService: aggregationsService}
 }
 
-// Handles the HTTP request to retrieve and return total sales data as JSON
+// Handles the HTTP request to retrieve and return total sales data as JSON, broken down
+// per product or variant when the group_by query parameter is set
 func (h *aggregationsHandler) TotalSales(w http.ResponseWriter, r *http.Request) {
-	total, err := h.aggregationsService.ServiceTotalSales()
+	groupBy := r.URL.Query().Get("group_by")
+	if groupBy != "" {
+		if err := service.CheckGroupBy(groupBy); err != nil {
+			SendError(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+	ReturnedTotal, err := h.aggregationsService.ServiceTotalSales()
 	if err != nil {
 		SendError(w, http.StatusInternalServerError, err)
 		return
 	}
-	ReturnedTotal := models.Total{
-		TotalSales: total,
+	if groupBy != "" {
+		ReturnedTotal.Breakdown, err = h.aggregationsService.ServiceSalesBreakdown(groupBy)
+		if err != nil {
+			SendError(w, http.StatusInternalServerError, err)
+			return
+		}
 	}
 	w.Header().Set("Content-Type", "application/json")
 	err = json.NewEncoder(w).Encode(ReturnedTotal)
@@ -39,9 +51,18 @@ func (h *aggregationsHandler) TotalSales(w http.ResponseWriter, r *http.Request)
 	}
 }
 
-// Handles the HTTP request to retrieve and return popular menu items as JSON
+// Handles the HTTP request to retrieve and return popular menu items as JSON, per product
+// or, with group_by=variant, per variant
 func (h *aggregationsHandler) PopularItems(w http.ResponseWriter, r *http.Request) {
-	err, res := h.aggregationsService.ServicePopularItems()
+	groupBy := r.URL.Query().Get("group_by")
+	if groupBy == "" {
+		groupBy = service.GroupByProduct
+	}
+	if err := service.CheckGroupBy(groupBy); err != nil {
+		SendError(w, http.StatusBadRequest, err)
+		return
+	}
+	err, res := h.aggregationsService.ServicePopularItems(groupBy)
 	if err != nil {
 		SendError(w, http.StatusInternalServerError, err)
 		return
@@ -55,3 +76,20 @@ func (h *aggregationsHandler) PopularItems(w http.ResponseWriter, r *http.Reques
 		return
 	}
 }
+
+// Handles the HTTP request for the tax charged per tax rate on the orders created between the
+// from and to query parameters
+func (h *aggregationsHandler) TaxReport(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	report, err := h.aggregationsService.ServiceTaxReport(query.Get("from"), query.Get("to"))
+	if err != nil {
+		SendError(w, http.StatusBadRequest, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(report)
+	if err != nil {
+		SendError(w, http.StatusInternalServerError, err)
+		return
+	}
+}
@@ -0,0 +1,85 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"hot-coffee/internal/service"
+	"hot-coffee/models"
+)
+
+type BackupHandler interface {
+	GetBackups(w http.ResponseWriter, r *http.Request)
+	PostBackups(w http.ResponseWriter, r *http.Request)
+	PostRestore(w http.ResponseWriter, r *http.Request)
+}
+
+type backupHandler struct {
+	backupService service.BackupService
+}
+
+// Initializes and returns a new instance of backupHandler with the provided service
+func NewBackupHandler(backupService service.BackupService) BackupHandler {
+	return &backupHandler{backupService: backupService}
+}
+
+// Handles the HTTP request to list the backup snapshots, newest first
+func (h *backupHandler) GetBackups(w http.ResponseWriter, r *http.Request) {
+	snapshots, err := h.backupService.ServiceListBackups()
+	if err != nil {
+		SendError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(snapshots)
+	if err != nil {
+		SendError(w, http.StatusInternalServerError, err)
+		return
+	}
+}
+
+// Handles the HTTP request to take a snapshot of the current data immediately
+func (h *backupHandler) PostBackups(w http.ResponseWriter, r *http.Request) {
+	snapshot, err := h.backupService.ServiceCreateBackup()
+	if err != nil {
+		SendError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	err = json.NewEncoder(w).Encode(snapshot)
+	if err != nil {
+		SendError(w, http.StatusInternalServerError, err)
+		return
+	}
+}
+
+// Handles the HTTP request to restore the data from a snapshot
+func (h *backupHandler) PostRestore(w http.ResponseWriter, r *http.Request) {
+	if err := CheckContentType(r); err != nil {
+		SendError(w, http.StatusBadRequest, err)
+		return
+	}
+	body := models.RestoreRequest{}
+	err := json.NewDecoder(r.Body).Decode(&body)
+	if err != nil {
+		SendError(w, http.StatusBadRequest, err)
+		return
+	}
+	if strings.TrimSpace(body.SnapshotID) == "" {
+		SendError(w, http.StatusBadRequest, errors.New("Missing snapshot id"))
+		return
+	}
+	err = h.backupService.ServiceRestore(body.SnapshotID)
+	if errors.Is(err, service.ErrSnapshotNotFound) {
+		SendError(w, http.StatusNotFound, err)
+		return
+	}
+	if err != nil {
+		SendError(w, http.StatusInternalServerError, err)
+		return
+	}
+	SendSucces(w, http.StatusOK, "Snapshot restored")
+}
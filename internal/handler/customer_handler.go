@@ -0,0 +1,192 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"hot-coffee/internal/service"
+	"hot-coffee/models"
+)
+
+type CustomerHandler interface {
+	PostCustomers(w http.ResponseWriter, r *http.Request)
+	GetCustomers(w http.ResponseWriter, r *http.Request)
+	GetCustomersID(w http.ResponseWriter, r *http.Request)
+	PutCustomersID(w http.ResponseWriter, r *http.Request)
+	DeleteCustomersID(w http.ResponseWriter, r *http.Request)
+	GetCustomersIDOrders(w http.ResponseWriter, r *http.Request)
+}
+
+type customerHandler struct {
+	customerService service.CustomerService
+	orderService    service.OrderService
+}
+
+// Initializes and returns a new instance of customerHandler with the provided services
+func NewCustomerHandler(customerService service.CustomerService, orderService service.OrderService) CustomerHandler {
+	return &customerHandler{customerService: customerService, orderService: orderService}
+}
+
+// Handles the HTTP request to register a new customer and returns the customer with its ID
+func (h *customerHandler) PostCustomers(w http.ResponseWriter, r *http.Request) {
+	if err := CheckContentType(r); err != nil {
+		SendError(w, http.StatusBadRequest, err)
+		return
+	}
+	body := models.Customer{}
+	err := json.NewDecoder(r.Body).Decode(&body)
+	if err != nil {
+		SendError(w, http.StatusBadRequest, err)
+		return
+	}
+	customer, err := h.customerService.CreateCustomer(body)
+	if err != nil {
+		SendError(w, http.StatusBadRequest, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	err = json.NewEncoder(w).Encode(customer)
+	if err != nil {
+		SendError(w, http.StatusInternalServerError, err)
+		return
+	}
+}
+
+// Handles the HTTP request to list the customers, optionally looked up by name, phone or email
+func (h *customerHandler) GetCustomers(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	query := models.CustomerQuery{
+		Name:  params.Get("name"),
+		Phone: params.Get("phone"),
+		Email: params.Get("email"),
+	}
+	customers, err := h.customerService.ListCustomers(query)
+	if err != nil {
+		SendError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(customers)
+	if err != nil {
+		SendError(w, http.StatusInternalServerError, err)
+		return
+	}
+}
+
+// Handles the HTTP request to retrieve a customer with their visit count and spending
+func (h *customerHandler) GetCustomersID(w http.ResponseWriter, r *http.Request) {
+	path := r.URL.Path
+	path = strings.Trim(path, "/")
+	parts := strings.SplitN(path, "/", 2)
+	if len(parts) != 2 {
+		err := errors.New("URL length")
+		SendError(w, http.StatusBadRequest, err)
+		return
+	}
+	customer, err := h.customerService.GetCustomer(parts[1])
+	if err != nil {
+		SendError(w, customerErrorStatus(err), err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(customer)
+	if err != nil {
+		SendError(w, http.StatusInternalServerError, err)
+		return
+	}
+}
+
+// Handles the HTTP request to change the name or contact details of a customer
+func (h *customerHandler) PutCustomersID(w http.ResponseWriter, r *http.Request) {
+	if err := CheckContentType(r); err != nil {
+		SendError(w, http.StatusBadRequest, err)
+		return
+	}
+	body := models.Customer{}
+	err := json.NewDecoder(r.Body).Decode(&body)
+	if err != nil {
+		SendError(w, http.StatusBadRequest, err)
+		return
+	}
+	path := r.URL.Path
+	path = strings.Trim(path, "/")
+	parts := strings.SplitN(path, "/", 2)
+	if len(parts) != 2 {
+		err := errors.New("URL length")
+		SendError(w, http.StatusBadRequest, err)
+		return
+	}
+	customer, err := h.customerService.UpdateCustomer(parts[1], body)
+	if err != nil {
+		SendError(w, customerErrorStatus(err), err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(customer)
+	if err != nil {
+		SendError(w, http.StatusInternalServerError, err)
+		return
+	}
+}
+
+// Handles the HTTP request to delete a customer without orders
+func (h *customerHandler) DeleteCustomersID(w http.ResponseWriter, r *http.Request) {
+	path := r.URL.Path
+	path = strings.Trim(path, "/")
+	parts := strings.SplitN(path, "/", 2)
+	if len(parts) != 2 {
+		err := errors.New("URL length")
+		SendError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := h.customerService.DeleteCustomer(parts[1]); err != nil {
+		SendError(w, customerErrorStatus(err), err)
+		return
+	}
+	SendSucces(w, http.StatusOK, "Customer deleted")
+}
+
+// Handles the HTTP request for the order history of a customer; it takes the filters,
+// sorting and paging of GET /orders
+func (h *customerHandler) GetCustomersIDOrders(w http.ResponseWriter, r *http.Request) {
+	path := r.URL.Path
+	path = strings.Trim(path, "/")
+	parts := strings.SplitN(path, "/", 3)
+	if len(parts) != 3 {
+		err := errors.New("URL length")
+		SendError(w, http.StatusBadRequest, err)
+		return
+	}
+	if _, err := h.customerService.GetCustomer(parts[1]); err != nil {
+		SendError(w, customerErrorStatus(err), err)
+		return
+	}
+	query, err := parseOrderQuery(r)
+	if err != nil {
+		SendError(w, http.StatusBadRequest, err)
+		return
+	}
+	query.CustomerID = parts[1]
+	page, err := h.orderService.QueryOrders(query)
+	if err != nil {
+		SendError(w, http.StatusBadRequest, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(page)
+	if err != nil {
+		SendError(w, http.StatusInternalServerError, err)
+		return
+	}
+}
+
+// Maps a customer error to its HTTP status: 404 for an unknown customer, 400 otherwise
+func customerErrorStatus(err error) int {
+	if errors.Is(err, service.ErrCustomerNotFound) {
+		return http.StatusNotFound
+	}
+	return http.StatusBadRequest
+}
@@ -0,0 +1,122 @@
+package handler
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"hot-coffee/internal/service"
+	"hot-coffee/models"
+)
+
+// Longest accepted Idempotency-Key
+const maxIdempotencyKeyLength = 255
+
+// Idempotent wraps a handler so that a request repeated with the same Idempotency-Key header
+// gets the stored response of the first one instead of running again. Requests without the
+// header run as usual. Only successes and client errors that a retry would repeat are
+// stored; other responses, and handlers that panic, release the key so the request can be retried.
+func Idempotent(idempotencyService service.IdempotencyService, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := strings.TrimSpace(r.Header.Get("Idempotency-Key"))
+		if key == "" {
+			next(w, r)
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			SendError(w, http.StatusBadRequest, errors.New("Idempotency key is too long"))
+			return
+		}
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			SendError(w, http.StatusBadRequest, err)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		hash := sha256.Sum256(body)
+		request := r.Method + " " + r.URL.Path
+
+		stored, err := idempotencyService.Begin(key, request, hex.EncodeToString(hash[:]))
+		switch {
+		case errors.Is(err, service.ErrIdempotencyKeyReused):
+			SendError(w, http.StatusUnprocessableEntity, err)
+			return
+		case errors.Is(err, service.ErrIdempotencyKeyInProgress):
+			SendError(w, http.StatusConflict, err)
+			return
+		case err != nil:
+			SendError(w, http.StatusInternalServerError, err)
+			return
+		case stored != nil:
+			slog.Info("Replayed idempotent response", slog.String("key", key), slog.String("request", request))
+			w.Header().Set("Content-Type", stored.ContentType)
+			w.Header().Set("Idempotent-Replayed", "true")
+			w.WriteHeader(stored.Status)
+			io.WriteString(w, stored.Body)
+			return
+		}
+
+		// The key is released unless a response is stored, also when next panics
+		completed := false
+		defer func() {
+			if !completed {
+				idempotencyService.Abandon(key)
+			}
+		}()
+		recorder := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+		next(recorder, r)
+		if !storableStatus(recorder.status) {
+			return
+		}
+		completed = true
+		err = idempotencyService.Complete(models.IdempotentResponse{
+			Key:         key,
+			Request:     request,
+			RequestHash: hex.EncodeToString(hash[:]),
+			Status:      recorder.status,
+			ContentType: w.Header().Get("Content-Type"),
+			Body:        recorder.body.String(),
+			CreatedAt:   time.Now(),
+		})
+		if err != nil {
+			slog.Error("Failed to store idempotent response", slog.String("key", key), slog.String("ERROR", err.Error()))
+		}
+	}
+}
+
+// Reports whether a response can be stored for an idempotency key: successes, and client
+// errors that depend only on the request. Conflicts, rate limits and timeouts, and any
+// other error, may go away on a retry.
+func storableStatus(status int) bool {
+	switch status {
+	case http.StatusBadRequest, http.StatusNotFound, http.StatusMethodNotAllowed, http.StatusRequestEntityTooLarge,
+		http.StatusUnsupportedMediaType, http.StatusUnprocessableEntity:
+		return true
+	}
+	return status >= 200 && status < 300
+}
+
+// responseRecorder passes a response through while keeping a copy of its status and body
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+// Records the status code and sends it
+func (r *responseRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// Records and sends a part of the body
+func (r *responseRecorder) Write(data []byte) (int, error) {
+	r.body.Write(data)
+	return r.ResponseWriter.Write(data)
+}
@@ -0,0 +1,95 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"hot-coffee/internal/config"
+	"hot-coffee/internal/dal"
+	"hot-coffee/internal/service"
+)
+
+// Returns an idempotency service storing its responses in a fresh data directory
+func newTestIdempotencyService(t *testing.T) service.IdempotencyService {
+	t.Helper()
+	cfg := config.Default()
+	cfg.DataDir = t.TempDir()
+	idempotencyService, err := service.NewIdempotencyService(dal.NewJSONIdempotencyRepository(cfg), time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return idempotencyService
+}
+
+// Sends a POST with an Idempotency-Key to a handler and returns the recorded response
+func postWithKey(handler http.HandlerFunc, key string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(`{"customer_name":"Ada"}`))
+	request.Header.Set("Idempotency-Key", key)
+	recorder := httptest.NewRecorder()
+	handler(recorder, request)
+	return recorder
+}
+
+// Successes and client errors a retry would repeat are replayed; other errors let the request run again
+func TestIdempotentStoresOnlyRepeatableResponses(t *testing.T) {
+	tests := []struct {
+		status int
+		stored bool
+	}{
+		{http.StatusCreated, true},
+		{http.StatusOK, true},
+		{http.StatusBadRequest, true},
+		{http.StatusNotFound, true},
+		{http.StatusUnprocessableEntity, true},
+		{http.StatusConflict, false},
+		{http.StatusTooManyRequests, false},
+		{http.StatusInternalServerError, false},
+		{http.StatusServiceUnavailable, false},
+	}
+	for _, test := range tests {
+		t.Run(http.StatusText(test.status), func(t *testing.T) {
+			runs := 0
+			handler := Idempotent(newTestIdempotencyService(t), func(w http.ResponseWriter, r *http.Request) {
+				runs++
+				w.WriteHeader(test.status)
+			})
+			first := postWithKey(handler, "key")
+			second := postWithKey(handler, "key")
+			if first.Code != test.status || second.Code != test.status {
+				t.Fatalf("statuses %d and %d, want %d", first.Code, second.Code, test.status)
+			}
+			if replayed := second.Header().Get("Idempotent-Replayed") == "true"; replayed != test.stored {
+				t.Errorf("retry replayed: %v, want %v", replayed, test.stored)
+			}
+			if wantRuns := map[bool]int{true: 1, false: 2}[test.stored]; runs != wantRuns {
+				t.Errorf("handler ran %d times, want %d", runs, wantRuns)
+			}
+		})
+	}
+}
+
+// A handler that panics releases its key, so the request can be retried
+func TestIdempotentReleasesKeyOnPanic(t *testing.T) {
+	panics := true
+	handler := Idempotent(newTestIdempotencyService(t), func(w http.ResponseWriter, r *http.Request) {
+		if panics {
+			panic("handler failed")
+		}
+		w.WriteHeader(http.StatusCreated)
+	})
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("handler did not panic")
+			}
+		}()
+		postWithKey(handler, "key")
+	}()
+	panics = false
+	if retry := postWithKey(handler, "key"); retry.Code != http.StatusCreated {
+		t.Errorf("retry after a panic got %d: %s", retry.Code, retry.Body)
+	}
+}
@@ -0,0 +1,196 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"hot-coffee/internal/service"
+	"hot-coffee/models"
+)
+
+type LoyaltyHandler interface {
+	GetRules(w http.ResponseWriter, r *http.Request)
+	PostRules(w http.ResponseWriter, r *http.Request)
+	PutRulesID(w http.ResponseWriter, r *http.Request)
+	DeleteRulesID(w http.ResponseWriter, r *http.Request)
+	GetCustomerBalances(w http.ResponseWriter, r *http.Request)
+	GetCustomerLedger(w http.ResponseWriter, r *http.Request)
+	PostCustomerAdjustment(w http.ResponseWriter, r *http.Request)
+}
+
+type loyaltyHandler struct {
+	loyaltyService service.LoyaltyService
+}
+
+// Initializes and returns a new instance of loyaltyHandler with the provided service
+func NewLoyaltyHandler(loyaltyService service.LoyaltyService) LoyaltyHandler {
+	return &loyaltyHandler{loyaltyService: loyaltyService}
+}
+
+// Handles the HTTP request to list the loyalty rules
+func (h *loyaltyHandler) GetRules(w http.ResponseWriter, r *http.Request) {
+	rules, err := h.loyaltyService.ListRules()
+	if err != nil {
+		SendError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(rules)
+	if err != nil {
+		SendError(w, http.StatusInternalServerError, err)
+		return
+	}
+}
+
+// Handles the HTTP request to add a loyalty rule
+func (h *loyaltyHandler) PostRules(w http.ResponseWriter, r *http.Request) {
+	if err := CheckContentType(r); err != nil {
+		SendError(w, http.StatusBadRequest, err)
+		return
+	}
+	body := models.LoyaltyRule{}
+	err := json.NewDecoder(r.Body).Decode(&body)
+	if err != nil {
+		SendError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := h.loyaltyService.CreateRule(body); err != nil {
+		SendError(w, http.StatusBadRequest, err)
+		return
+	}
+	SendSucces(w, http.StatusCreated, "Loyalty rule added")
+}
+
+// Handles the HTTP request to replace a loyalty rule
+func (h *loyaltyHandler) PutRulesID(w http.ResponseWriter, r *http.Request) {
+	if err := CheckContentType(r); err != nil {
+		SendError(w, http.StatusBadRequest, err)
+		return
+	}
+	body := models.LoyaltyRule{}
+	err := json.NewDecoder(r.Body).Decode(&body)
+	if err != nil {
+		SendError(w, http.StatusBadRequest, err)
+		return
+	}
+	path := r.URL.Path
+	path = strings.Trim(path, "/")
+	parts := strings.SplitN(path, "/", 3)
+	if len(parts) != 3 {
+		err := errors.New("URL length")
+		SendError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := h.loyaltyService.UpdateRule(parts[2], body); err != nil {
+		SendError(w, loyaltyErrorStatus(err), err)
+		return
+	}
+	SendSucces(w, http.StatusOK, "Loyalty rule updated")
+}
+
+// Handles the HTTP request to delete a loyalty rule
+func (h *loyaltyHandler) DeleteRulesID(w http.ResponseWriter, r *http.Request) {
+	path := r.URL.Path
+	path = strings.Trim(path, "/")
+	parts := strings.SplitN(path, "/", 3)
+	if len(parts) != 3 {
+		err := errors.New("URL length")
+		SendError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := h.loyaltyService.DeleteRule(parts[2]); err != nil {
+		SendError(w, loyaltyErrorStatus(err), err)
+		return
+	}
+	SendSucces(w, http.StatusOK, "Loyalty rule deleted")
+}
+
+// Handles the HTTP request for a customer's balance under every loyalty rule
+func (h *loyaltyHandler) GetCustomerBalances(w http.ResponseWriter, r *http.Request) {
+	path := r.URL.Path
+	path = strings.Trim(path, "/")
+	parts := strings.SplitN(path, "/", 3)
+	if len(parts) != 3 {
+		err := errors.New("URL length")
+		SendError(w, http.StatusBadRequest, err)
+		return
+	}
+	balances, err := h.loyaltyService.Balances(parts[1])
+	if err != nil {
+		SendError(w, loyaltyErrorStatus(err), err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(balances)
+	if err != nil {
+		SendError(w, http.StatusInternalServerError, err)
+		return
+	}
+}
+
+// Handles the HTTP request for the history of a customer's loyalty balances
+func (h *loyaltyHandler) GetCustomerLedger(w http.ResponseWriter, r *http.Request) {
+	path := r.URL.Path
+	path = strings.Trim(path, "/")
+	parts := strings.SplitN(path, "/", 4)
+	if len(parts) != 4 {
+		err := errors.New("URL length")
+		SendError(w, http.StatusBadRequest, err)
+		return
+	}
+	entries, err := h.loyaltyService.Ledger(parts[1])
+	if err != nil {
+		SendError(w, loyaltyErrorStatus(err), err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(entries)
+	if err != nil {
+		SendError(w, http.StatusInternalServerError, err)
+		return
+	}
+}
+
+// Handles the HTTP request to credit or debit a customer's loyalty balance by hand
+func (h *loyaltyHandler) PostCustomerAdjustment(w http.ResponseWriter, r *http.Request) {
+	if err := CheckContentType(r); err != nil {
+		SendError(w, http.StatusBadRequest, err)
+		return
+	}
+	body := models.LoyaltyAdjustment{}
+	err := json.NewDecoder(r.Body).Decode(&body)
+	if err != nil {
+		SendError(w, http.StatusBadRequest, err)
+		return
+	}
+	path := r.URL.Path
+	path = strings.Trim(path, "/")
+	parts := strings.SplitN(path, "/", 4)
+	if len(parts) != 4 {
+		err := errors.New("URL length")
+		SendError(w, http.StatusBadRequest, err)
+		return
+	}
+	entry, err := h.loyaltyService.Adjust(parts[1], body)
+	if err != nil {
+		SendError(w, loyaltyErrorStatus(err), err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	err = json.NewEncoder(w).Encode(entry)
+	if err != nil {
+		SendError(w, http.StatusInternalServerError, err)
+		return
+	}
+}
+
+// Maps a loyalty error to its HTTP status: 404 for an unknown customer or rule, 400 otherwise
+func loyaltyErrorStatus(err error) int {
+	if errors.Is(err, service.ErrCustomerNotFound) || errors.Is(err, service.ErrLoyaltyRuleNotFound) {
+		return http.StatusNotFound
+	}
+	return http.StatusBadRequest
+}
@@ -3,7 +3,10 @@ package handler
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
+	"log/slog"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"hot-coffee/internal/service"
@@ -17,6 +20,12 @@ type OrderHandler interface {
 	PutOrdersID(w http.ResponseWriter, r *http.Request)
 	DeleteOrdersID(w http.ResponseWriter, r *http.Request)
 	PostOrdersIDClose(w http.ResponseWriter, r *http.Request)
+	PostOrdersIDPayments(w http.ResponseWriter, r *http.Request)
+	GetOrdersIDPayments(w http.ResponseWriter, r *http.Request)
+	PostOrdersIDTransition(w http.ResponseWriter, r *http.Request)
+	PostOrdersIDCancel(w http.ResponseWriter, r *http.Request)
+	PostOrdersIDRefund(w http.ResponseWriter, r *http.Request)
+	PostOrdersQuote(w http.ResponseWriter, r *http.Request)
 }
 type orderHandler struct {
 	orderService service.OrderService
@@ -27,38 +36,68 @@ func NewOrderHandler(orderService service.OrderService) OrderHandler {
 	return &orderHandler{orderService: orderService}
 }
 
-// Handles the HTTP request to create a new order, validating input and returning success or error
+// Handles the HTTP request to create a new order, validating input and returning the created order
 func (h orderHandler) PostOrders(w http.ResponseWriter, r *http.Request) {
 	if err := CheckContentType(r); err != nil {
 		SendError(w, http.StatusBadRequest, err)
 		return
 	}
-	body := models.Order{}
+	body := models.OrderRequest{}
 	err := json.NewDecoder(r.Body).Decode(&body)
 	if err != nil {
 		SendError(w, http.StatusBadRequest, err)
 		return
 	}
 
-	err = h.orderService.ServicePostOrders(body)
+	order, err := h.orderService.ServicePostOrders(body)
 	if err != nil {
+		SendError(w, orderErrorStatus(err), err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(order); err != nil {
+		slog.Error("Failed to send response", slog.String("ERROR", err.Error()))
+	}
+}
 
+// Handles the HTTP request to price a prospective order and check the stock for it without creating it
+func (h orderHandler) PostOrdersQuote(w http.ResponseWriter, r *http.Request) {
+	if err := CheckContentType(r); err != nil {
 		SendError(w, http.StatusBadRequest, err)
 		return
 	}
-
-	SendSucces(w, http.StatusCreated, "Order opened")
+	body := models.OrderRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		SendError(w, http.StatusBadRequest, err)
+		return
+	}
+	quote, err := h.orderService.QuoteOrder(body)
+	if err != nil {
+		SendError(w, http.StatusBadRequest, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(quote); err != nil {
+		SendError(w, http.StatusInternalServerError, err)
+		return
+	}
 }
 
-// Handles the HTTP request to retrieve all orders and returns them as JSON
+// Handles the HTTP request to retrieve one page of the orders matching the query parameters and returns it as JSON
 func (h orderHandler) GetOrders(w http.ResponseWriter, r *http.Request) {
-	orders, err := h.orderService.GetOrdersService()
+	query, err := parseOrderQuery(r)
 	if err != nil {
-		SendError(w, http.StatusInternalServerError, err)
+		SendError(w, http.StatusBadRequest, err)
+		return
+	}
+	page, err := h.orderService.QueryOrders(query)
+	if err != nil {
+		SendError(w, http.StatusBadRequest, err)
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
-	err = json.NewEncoder(w).Encode(orders)
+	err = json.NewEncoder(w).Encode(page)
 	if err != nil {
 		SendError(w, http.StatusInternalServerError, err)
 		return
@@ -146,8 +185,189 @@ func (h orderHandler) PostOrdersIDClose(w http.ResponseWriter, r *http.Request)
 		return
 	}
 	if err := h.orderService.CloseOrder(parts[1]); err != nil {
-		SendError(w, http.StatusBadRequest, err)
+		SendError(w, transitionErrorStatus(err), err)
 		return
 	}
 	SendSucces(w, http.StatusOK, "Order closed")
 }
+
+// Handles the HTTP request to record one or more payments against a specific order and returns
+// the order's payments and remaining balance
+func (h orderHandler) PostOrdersIDPayments(w http.ResponseWriter, r *http.Request) {
+	if err := CheckContentType(r); err != nil {
+		SendError(w, http.StatusBadRequest, err)
+		return
+	}
+	body := models.PaymentRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		SendError(w, http.StatusBadRequest, err)
+		return
+	}
+	path := r.URL.Path
+	path = strings.Trim(path, "/")
+	parts := strings.SplitN(path, "/", 3)
+	if len(parts) != 3 {
+		err := errors.New("URL length")
+		SendError(w, http.StatusBadRequest, err)
+		return
+	}
+	payments, err := h.orderService.PayOrder(parts[1], body.Tenders)
+	if err != nil {
+		SendError(w, orderErrorStatus(err), err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	err = json.NewEncoder(w).Encode(payments)
+	if err != nil {
+		SendError(w, http.StatusInternalServerError, err)
+		return
+	}
+}
+
+// Handles the HTTP request for the payments and remaining balance of a specific order
+func (h orderHandler) GetOrdersIDPayments(w http.ResponseWriter, r *http.Request) {
+	path := r.URL.Path
+	path = strings.Trim(path, "/")
+	parts := strings.SplitN(path, "/", 3)
+	if len(parts) != 3 {
+		err := errors.New("URL length")
+		SendError(w, http.StatusBadRequest, err)
+		return
+	}
+	payments, err := h.orderService.OrderPayments(parts[1])
+	if err != nil {
+		SendError(w, http.StatusBadRequest, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(payments)
+	if err != nil {
+		SendError(w, http.StatusInternalServerError, err)
+		return
+	}
+}
+
+// Handles the HTTP request to move a specific order to another status
+func (h orderHandler) PostOrdersIDTransition(w http.ResponseWriter, r *http.Request) {
+	if err := CheckContentType(r); err != nil {
+		SendError(w, http.StatusBadRequest, err)
+		return
+	}
+	body := models.TransitionRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		SendError(w, http.StatusBadRequest, err)
+		return
+	}
+	path := r.URL.Path
+	path = strings.Trim(path, "/")
+	parts := strings.SplitN(path, "/", 3)
+	if len(parts) != 3 {
+		err := errors.New("URL length")
+		SendError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := h.orderService.TransitionOrder(parts[1], body.Status); err != nil {
+		SendError(w, transitionErrorStatus(err), err)
+		return
+	}
+	SendSucces(w, http.StatusOK, "Order status changed to "+body.Status)
+}
+
+// Handles the HTTP request to cancel a specific active order with a reason
+func (h orderHandler) PostOrdersIDCancel(w http.ResponseWriter, r *http.Request) {
+	body, id, err := decodeCancelRequest(r)
+	if err != nil {
+		SendError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := h.orderService.CancelOrder(id, body.Reason); err != nil {
+		SendError(w, transitionErrorStatus(err), err)
+		return
+	}
+	SendSucces(w, http.StatusOK, "Order cancelled")
+}
+
+// Handles the HTTP request to refund a specific closed order with a reason, optionally restocking its ingredients
+func (h orderHandler) PostOrdersIDRefund(w http.ResponseWriter, r *http.Request) {
+	body, id, err := decodeCancelRequest(r)
+	if err != nil {
+		SendError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := h.orderService.RefundOrder(id, body.Reason, body.Restock); err != nil {
+		SendError(w, transitionErrorStatus(err), err)
+		return
+	}
+	SendSucces(w, http.StatusOK, "Order refunded")
+}
+
+// Reads the body and the order ID of a cancel or refund request
+func decodeCancelRequest(r *http.Request) (models.CancelRequest, string, error) {
+	body := models.CancelRequest{}
+	if err := CheckContentType(r); err != nil {
+		return body, "", err
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		return body, "", err
+	}
+	path := r.URL.Path
+	path = strings.Trim(path, "/")
+	parts := strings.SplitN(path, "/", 3)
+	if len(parts) != 3 {
+		return body, "", errors.New("URL length")
+	}
+	return body, parts[1], nil
+}
+
+// Returns 409 Conflict for transitions the order lifecycle does not allow, or closing an order
+// that is not fully paid, 500 for failures to access the data files and 400 otherwise
+func transitionErrorStatus(err error) int {
+	if errors.Is(err, service.ErrIllegalTransition) || errors.Is(err, service.ErrUnpaidBalance) {
+		return http.StatusConflict
+	}
+	return orderErrorStatus(err)
+}
+
+// Returns 500 for failures to access the data files and 400 for invalid requests
+func orderErrorStatus(err error) int {
+	if errors.Is(err, service.ErrStorage) {
+		return http.StatusInternalServerError
+	}
+	return http.StatusBadRequest
+}
+
+// Reads the filters, sorting and paging of GET /orders from the query parameters
+func parseOrderQuery(r *http.Request) (models.OrderQuery, error) {
+	params := r.URL.Query()
+	query := models.OrderQuery{
+		CustomerName: params.Get("customer_name"),
+		CustomerID:   params.Get("customer_id"),
+		ProductID:    params.Get("product_id"),
+		CreatedFrom:  params.Get("created_from"),
+		CreatedTo:    params.Get("created_to"),
+		PickupFrom:   params.Get("pickup_from"),
+		PickupTo:     params.Get("pickup_to"),
+		SortBy:       params.Get("sort"),
+	}
+	if statuses := params.Get("status"); statuses != "" {
+		query.Statuses = strings.Split(statuses, ",")
+	}
+	switch params.Get("order") {
+	case "", "asc":
+	case "desc":
+		query.Descending = true
+	default:
+		return query, errors.New("Order must be asc or desc")
+	}
+	for name, field := range map[string]*int{"offset": &query.Offset, "limit": &query.Limit} {
+		if value := params.Get(name); value != "" {
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return query, fmt.Errorf("Invalid %s: %s", name, value)
+			}
+			*field = n
+		}
+	}
+	return query, nil
+}
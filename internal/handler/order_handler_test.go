@@ -0,0 +1,427 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"hot-coffee/internal/config"
+	"hot-coffee/internal/dal"
+	"hot-coffee/internal/service"
+	"hot-coffee/models"
+)
+
+// Stock the test inventory starts with
+const (
+	testShots = 1000.0
+	testMilk  = 1000000.0
+)
+
+// A server over a fresh data directory holding one latte on the menu and its ingredients
+type testServer struct {
+	*httptest.Server
+	cfg config.Config
+}
+
+// Starts the order, queue, menu and inventory routes over one store, as the server wires them
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+	dir := t.TempDir()
+	cfg := config.Default()
+	cfg.DataDir, cfg.SeedDir, cfg.BackupDir = dir, dir, filepath.Join(dir, "backups")
+	writeTestJSON(t, cfg.MenuPath(), []models.MenuItem{testLatte(3.5)})
+	writeTestJSON(t, cfg.InventoryPath(), []models.InventoryItem{
+		{IngredientID: "espresso_shot", Name: "Espresso Shot", Quantity: testShots, Unit: "shots"},
+		{IngredientID: "milk", Name: "Milk", Quantity: testMilk, Unit: "ml"},
+	})
+	if err := dal.Bootstrap(cfg); err != nil {
+		t.Fatal(err)
+	}
+	store, err := dal.LoadStore(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	locks := dal.NewFileLocks()
+
+	orderRepo := dal.NewJSONOrderRepository(store, locks)
+	idGen, err := service.NewIDGenerator(cfg.IDStrategy, dal.NewJSONSequenceRepository(cfg), orderRepo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	orderService := service.NewOrderService(orderRepo, idGen, 0, time.Duration(cfg.PickupLeadTime), service.NewEventBus())
+	orderHandler := NewOrderHandler(orderService)
+	menuHandler := NewMenuHandler(service.NewMenuService(dal.NewJSONMenuRepository(store, locks)))
+	invHandler := NewInvHandler(service.NewInvService(dal.NewJSONInvRepository(store, locks)))
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /orders", orderHandler.PostOrders)
+	mux.HandleFunc("POST /orders/quote", orderHandler.PostOrdersQuote)
+	mux.HandleFunc("GET /orders", orderHandler.GetOrders)
+	mux.HandleFunc("GET /orders/{id}", orderHandler.GetOrdersID)
+	mux.HandleFunc("PUT /orders/{id}", orderHandler.PutOrdersID)
+	mux.HandleFunc("POST /orders/{id}/close", orderHandler.PostOrdersIDClose)
+	mux.HandleFunc("POST /orders/{id}/payments", orderHandler.PostOrdersIDPayments)
+	mux.HandleFunc("GET /queue/stream", NewQueueHandler(orderService).StreamQueue)
+	mux.HandleFunc("PUT /menu/{id}", menuHandler.PutMenuID)
+	mux.HandleFunc("GET /inventory", invHandler.GetInv)
+
+	server := &testServer{Server: httptest.NewServer(mux), cfg: cfg}
+	t.Cleanup(server.Close)
+	return server
+}
+
+// Returns the test menu's latte at the given price
+func testLatte(price float64) models.MenuItem {
+	return models.MenuItem{
+		ID: "latte", Name: "Latte", Description: "Espresso with steamed milk", Price: price,
+		Ingredients: []models.MenuItemIngredient{{IngredientID: "espresso_shot", Quantity: 1}, {IngredientID: "milk", Quantity: 200}},
+	}
+}
+
+// Writes a value as a JSON data file
+func writeTestJSON(t *testing.T, path string, value any) {
+	t.Helper()
+	data, err := json.Marshal(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Sends a request with an optional JSON body and returns the status and response body. It is
+// safe to call from any goroutine, so it reports failures with Errorf.
+func (s *testServer) do(t *testing.T, method string, path string, body any) (int, []byte) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			t.Errorf("%s %s: %v", method, path, err)
+			return 0, nil
+		}
+		reader = bytes.NewReader(data)
+	}
+	request, err := http.NewRequest(method, s.URL+path, reader)
+	if err != nil {
+		t.Errorf("%s %s: %v", method, path, err)
+		return 0, nil
+	}
+	request.Header.Set("Content-Type", "application/json")
+	response, err := s.Client().Do(request)
+	if err != nil {
+		t.Errorf("%s %s: %v", method, path, err)
+		return 0, nil
+	}
+	defer response.Body.Close()
+	data, err := io.ReadAll(response.Body)
+	if err != nil {
+		t.Errorf("%s %s: %v", method, path, err)
+	}
+	return response.StatusCode, data
+}
+
+// Returns all orders
+func (s *testServer) orders(t *testing.T) []models.Order {
+	t.Helper()
+	status, data := s.do(t, http.MethodGet, "/orders?limit=500", nil)
+	page := models.OrderPage{}
+	if err := json.Unmarshal(data, &page); status != http.StatusOK || err != nil {
+		t.Fatalf("GET /orders: %d %s", status, data)
+	}
+	return page.Orders
+}
+
+// Returns the inventory by ingredient ID
+func (s *testServer) inventory(t *testing.T) map[string]models.InventoryItem {
+	t.Helper()
+	status, data := s.do(t, http.MethodGet, "/inventory", nil)
+	var items []models.InventoryItem
+	if err := json.Unmarshal(data, &items); status != http.StatusOK || err != nil {
+		t.Fatalf("GET /inventory: %d %s", status, data)
+	}
+	result := make(map[string]models.InventoryItem, len(items))
+	for _, item := range items {
+		result[item.IngredientID] = item
+	}
+	return result
+}
+
+// Places n one-latte orders in parallel and returns them
+func (s *testServer) placeOrders(t *testing.T, n int) []models.Order {
+	t.Helper()
+	var wg sync.WaitGroup
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			body := models.Order{CustomerName: fmt.Sprintf("Customer %d", i), Items: []models.OrderItem{{ProductID: "latte", Quantity: 1}}}
+			if status, data := s.do(t, http.MethodPost, "/orders", body); status != http.StatusCreated {
+				t.Errorf("POST /orders: %d %s", status, data)
+			}
+		}()
+	}
+	wg.Wait()
+	orders := s.orders(t)
+	if len(orders) != n {
+		t.Fatalf("got %d orders, want %d", len(orders), n)
+	}
+	return orders
+}
+
+// Checks that the data files hold what the server serves from memory
+func (s *testServer) checkDisk(t *testing.T) {
+	t.Helper()
+	reloaded, err := dal.LoadStore(s.cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	served := s.orders(t)
+	onDisk := reloaded.Orders()
+	if len(onDisk) != len(served) {
+		t.Fatalf("%d orders on disk, %d served", len(onDisk), len(served))
+	}
+	for _, order := range served {
+		saved, exists := reloaded.Order(order.ID)
+		if !exists || saved.Status != order.Status || saved.Total != order.Total {
+			t.Errorf("order %s on disk is %+v, served %+v", order.ID, saved, order)
+		}
+	}
+	inventory := s.inventory(t)
+	for _, item := range reloaded.Inventory() {
+		if served := inventory[item.IngredientID]; item != served {
+			t.Errorf("inventory on disk is %+v, served %+v", item, served)
+		}
+	}
+}
+
+// Parallel creates get distinct IDs, and two parallel closes of the same order deduct its
+// ingredients once: one succeeds and the other is refused
+func TestParallelCreateAndClose(t *testing.T) {
+	s := newTestServer(t)
+	const n = 20
+	orders := s.placeOrders(t, n)
+	seen := make(map[string]bool)
+	for _, order := range orders {
+		if seen[order.ID] {
+			t.Fatalf("order ID %s issued twice", order.ID)
+		}
+		seen[order.ID] = true
+		payment := models.PaymentRequest{Tenders: []models.Payment{{Method: models.TenderCard, Amount: order.Total}}}
+		if status, data := s.do(t, http.MethodPost, "/orders/"+order.ID+"/payments", payment); status != http.StatusCreated {
+			t.Fatalf("paying order %s: %d %s", order.ID, status, data)
+		}
+	}
+	if inventory := s.inventory(t); inventory["espresso_shot"].Reserved != n {
+		t.Fatalf("%v shots reserved, want %d", inventory["espresso_shot"].Reserved, n)
+	}
+
+	var mu sync.Mutex
+	closed := make(map[string]int)
+	var wg sync.WaitGroup
+	for _, order := range orders {
+		for range 2 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				status, data := s.do(t, http.MethodPost, "/orders/"+order.ID+"/close", nil)
+				switch status {
+				case http.StatusOK:
+					mu.Lock()
+					closed[order.ID]++
+					mu.Unlock()
+				case http.StatusConflict:
+				default:
+					t.Errorf("closing order %s: %d %s", order.ID, status, data)
+				}
+			}()
+		}
+	}
+	wg.Wait()
+	for _, order := range orders {
+		if closed[order.ID] != 1 {
+			t.Errorf("order %s closed %d times", order.ID, closed[order.ID])
+		}
+	}
+
+	inventory := s.inventory(t)
+	if shots := inventory["espresso_shot"]; shots.Quantity != testShots-n || shots.Reserved != 0 {
+		t.Errorf("espresso shots %+v, want %v on hand and none reserved", shots, testShots-n)
+	}
+	if milk := inventory["milk"]; milk.Quantity != testMilk-200*n || milk.Reserved != 0 {
+		t.Errorf("milk %+v, want %v on hand and none reserved", milk, testMilk-200*n)
+	}
+	for _, order := range s.orders(t) {
+		if order.Status != models.StatusClosed {
+			t.Errorf("order %s is %s, want closed", order.ID, order.Status)
+		}
+	}
+	s.checkDisk(t)
+}
+
+// Parallel updates of the same orders, racing menu price changes, leave every order priced
+// at one menu price and the inventory reserving exactly what the orders hold
+func TestParallelUpdatesKeepReservations(t *testing.T) {
+	s := newTestServer(t)
+	orders := s.placeOrders(t, 5)
+
+	var wg sync.WaitGroup
+	for _, order := range orders {
+		for quantity := 1; quantity <= 8; quantity++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				body := models.Order{Items: []models.OrderItem{{ProductID: "latte", Quantity: quantity}}}
+				if status, data := s.do(t, http.MethodPut, "/orders/"+order.ID, body); status != http.StatusOK {
+					t.Errorf("updating order %s: %d %s", order.ID, status, data)
+				}
+			}()
+		}
+	}
+	for _, price := range []float64{4, 3.5, 4, 3.5} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if status, data := s.do(t, http.MethodPut, "/menu/latte", testLatte(price)); status != http.StatusOK {
+				t.Errorf("updating the latte price: %d %s", status, data)
+			}
+		}()
+	}
+	wg.Wait()
+
+	reserved := 0.0
+	for _, order := range s.orders(t) {
+		item := order.Items[0]
+		if item.UnitPrice != 3.5 && item.UnitPrice != 4 {
+			t.Errorf("order %s priced at %v", order.ID, item.UnitPrice)
+		}
+		if item.LineTotal != item.UnitPrice*float64(item.Quantity) || order.Total != item.LineTotal {
+			t.Errorf("order %s totals do not match its price: %+v", order.ID, order)
+		}
+		if order.Reserved["espresso_shot"] != float64(item.Quantity) {
+			t.Errorf("order %s reserves %v shots for %d lattes", order.ID, order.Reserved["espresso_shot"], item.Quantity)
+		}
+		reserved += order.Reserved["espresso_shot"]
+	}
+	if shots := s.inventory(t)["espresso_shot"]; shots.Reserved != reserved || shots.Quantity != testShots {
+		t.Errorf("espresso shots %+v, want %v reserved and %v on hand", shots, reserved, testShots)
+	}
+	s.checkDisk(t)
+}
+
+// Fields the server owns are ignored when an order is placed, so a client cannot mark its
+// order paid and close it without paying
+func TestPostOrderIgnoresServerFields(t *testing.T) {
+	s := newTestServer(t)
+	body := map[string]any{
+		"order_id":             "forged",
+		"customer_name":        "Mallory",
+		"items":                []map[string]any{{"product_id": "latte", "quantity": 1, "unit_price": 0.01, "discount": 3.5}},
+		"status":               models.StatusReady,
+		"payments":             []models.Payment{{ID: "1", Method: models.TenderCash, Amount: 100}},
+		"paid":                 100,
+		"tips":                 20,
+		"discounts":            []models.AppliedDiscount{{PromotionID: "forged", Amount: 3.5}},
+		"taxes":                []models.AppliedTax{{TaxRateID: "forged", Amount: 1}},
+		"reserved_ingredients": map[string]float64{},
+		"consumed_ingredients": map[string]float64{"espresso_shot": 1},
+		"restocked":            true,
+		"reason":               "forged",
+	}
+	if status, data := s.do(t, http.MethodPost, "/orders", body); status != http.StatusCreated {
+		t.Fatalf("POST /orders: %d %s", status, data)
+	}
+	orders := s.orders(t)
+	if len(orders) != 1 {
+		t.Fatalf("got %d orders, want 1", len(orders))
+	}
+	order := orders[0]
+	if order.ID == "forged" || order.Status != models.StatusOpen || order.Total != 3.5 {
+		t.Errorf("order kept client-set fields: %+v", order)
+	}
+	if order.Paid != 0 || order.Tips != 0 || len(order.Payments) != 0 || len(order.Discounts) != 0 || len(order.Taxes) != 0 {
+		t.Errorf("order kept client-set payments, discounts or taxes: %+v", order)
+	}
+	if len(order.Consumed) != 0 || order.Restocked || order.Reason != "" || order.Reserved["espresso_shot"] != 1 {
+		t.Errorf("order kept client-set stock fields: %+v", order)
+	}
+
+	status, data := s.do(t, http.MethodPost, "/orders/"+order.ID+"/close", nil)
+	if status != http.StatusConflict || !bytes.Contains(data, []byte(service.ErrUnpaidBalance.Error())) {
+		t.Errorf("closing the unpaid order: %d %s, want %d %q", status, data, http.StatusConflict, service.ErrUnpaidBalance)
+	}
+}
+
+// A created order is returned as it was saved
+func TestPostOrderReturnsOrder(t *testing.T) {
+	s := newTestServer(t)
+	body := models.OrderRequest{CustomerName: "Ada", Items: []models.OrderItem{{ProductID: "latte", Quantity: 2}}}
+	status, data := s.do(t, http.MethodPost, "/orders", body)
+	var created models.Order
+	if err := json.Unmarshal(data, &created); status != http.StatusCreated || err != nil {
+		t.Fatalf("POST /orders: %d %s", status, data)
+	}
+	orders := s.orders(t)
+	if len(orders) != 1 || created.ID != orders[0].ID || created.Status != models.StatusOpen || created.Total != orders[0].Total {
+		t.Errorf("returned %+v, saved %+v", created, orders)
+	}
+}
+
+// A quote takes the same body as POST /orders and prices it as the created order is priced,
+// ignoring the fields the server sets
+func TestQuoteMatchesCreatedOrder(t *testing.T) {
+	s := newTestServer(t)
+	body := map[string]any{
+		"order_id":      "forged",
+		"customer_name": "Ada",
+		"items":         []map[string]any{{"product_id": "latte", "quantity": 2, "unit_price": 0.01, "discount": 7}},
+		"status":        models.StatusClosed,
+		"discounts":     []models.AppliedDiscount{{PromotionID: "forged", Amount: 7}},
+		"discount":      7,
+		"total":         0,
+	}
+	status, data := s.do(t, http.MethodPost, "/orders/quote", body)
+	var quote models.Quote
+	if err := json.Unmarshal(data, &quote); status != http.StatusOK || err != nil {
+		t.Fatalf("POST /orders/quote: %d %s", status, data)
+	}
+	status, data = s.do(t, http.MethodPost, "/orders", body)
+	var created models.Order
+	if err := json.Unmarshal(data, &created); status != http.StatusCreated || err != nil {
+		t.Fatalf("POST /orders: %d %s", status, data)
+	}
+	if quote.Total != 7 || quote.Discount != 0 || len(quote.Discounts) != 0 || quote.Items[0].UnitPrice != 3.5 {
+		t.Errorf("quote kept client-set prices: %+v", quote)
+	}
+	if quote.Total != created.Total || quote.Subtotal != created.Subtotal || quote.Tax != created.Tax {
+		t.Errorf("quoted %+v, created %+v", quote, created)
+	}
+	if status, data := s.do(t, http.MethodPost, "/orders/quote", map[string]any{"customer_name": "Ada", "items": "latte"}); status != http.StatusBadRequest {
+		t.Errorf("POST /orders/quote with malformed items: %d %s, want %d", status, data, http.StatusBadRequest)
+	}
+}
+
+// An order that cannot be saved is a server error, not a client one
+func TestPostOrderStorageFailure(t *testing.T) {
+	s := newTestServer(t)
+	// A directory in the journal's place makes every commit fail
+	if err := os.Mkdir(filepath.Join(s.cfg.DataDir, "tx-journal.json"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	body := models.OrderRequest{CustomerName: "Ada", Items: []models.OrderItem{{ProductID: "latte", Quantity: 1}}}
+	if status, data := s.do(t, http.MethodPost, "/orders", body); status != http.StatusInternalServerError {
+		t.Errorf("POST /orders: %d %s, want %d", status, data, http.StatusInternalServerError)
+	}
+	body.Items[0].ProductID = "mocha"
+	if status, data := s.do(t, http.MethodPost, "/orders", body); status != http.StatusBadRequest {
+		t.Errorf("POST /orders with an unknown product: %d %s, want %d", status, data, http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,137 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"hot-coffee/internal/service"
+	"hot-coffee/models"
+)
+
+type PromotionHandler interface {
+	GetPromotions(w http.ResponseWriter, r *http.Request)
+	PostPromotions(w http.ResponseWriter, r *http.Request)
+	GetPromotionsID(w http.ResponseWriter, r *http.Request)
+	PutPromotionsID(w http.ResponseWriter, r *http.Request)
+	DeletePromotionsID(w http.ResponseWriter, r *http.Request)
+}
+
+type promotionHandler struct {
+	promotionService service.PromotionService
+}
+
+// Initializes and returns a new instance of promotionHandler with the provided service
+func NewPromotionHandler(promotionService service.PromotionService) PromotionHandler {
+	return &promotionHandler{promotionService: promotionService}
+}
+
+// Handles the HTTP request to list the promotions
+func (h *promotionHandler) GetPromotions(w http.ResponseWriter, r *http.Request) {
+	promotions, err := h.promotionService.ListPromotions()
+	if err != nil {
+		SendError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(promotions)
+	if err != nil {
+		SendError(w, http.StatusInternalServerError, err)
+		return
+	}
+}
+
+// Handles the HTTP request to add a promotion
+func (h *promotionHandler) PostPromotions(w http.ResponseWriter, r *http.Request) {
+	if err := CheckContentType(r); err != nil {
+		SendError(w, http.StatusBadRequest, err)
+		return
+	}
+	body := models.Promotion{}
+	err := json.NewDecoder(r.Body).Decode(&body)
+	if err != nil {
+		SendError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := h.promotionService.CreatePromotion(body); err != nil {
+		SendError(w, http.StatusBadRequest, err)
+		return
+	}
+	SendSucces(w, http.StatusCreated, "Promotion added")
+}
+
+// Handles the HTTP request for one promotion
+func (h *promotionHandler) GetPromotionsID(w http.ResponseWriter, r *http.Request) {
+	path := r.URL.Path
+	path = strings.Trim(path, "/")
+	parts := strings.SplitN(path, "/", 2)
+	if len(parts) != 2 {
+		err := errors.New("URL length")
+		SendError(w, http.StatusBadRequest, err)
+		return
+	}
+	promotion, err := h.promotionService.GetPromotion(parts[1])
+	if err != nil {
+		SendError(w, promotionErrorStatus(err), err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(promotion)
+	if err != nil {
+		SendError(w, http.StatusInternalServerError, err)
+		return
+	}
+}
+
+// Handles the HTTP request to replace a promotion
+func (h *promotionHandler) PutPromotionsID(w http.ResponseWriter, r *http.Request) {
+	if err := CheckContentType(r); err != nil {
+		SendError(w, http.StatusBadRequest, err)
+		return
+	}
+	body := models.Promotion{}
+	err := json.NewDecoder(r.Body).Decode(&body)
+	if err != nil {
+		SendError(w, http.StatusBadRequest, err)
+		return
+	}
+	path := r.URL.Path
+	path = strings.Trim(path, "/")
+	parts := strings.SplitN(path, "/", 2)
+	if len(parts) != 2 {
+		err := errors.New("URL length")
+		SendError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := h.promotionService.UpdatePromotion(parts[1], body); err != nil {
+		SendError(w, promotionErrorStatus(err), err)
+		return
+	}
+	SendSucces(w, http.StatusOK, "Promotion updated")
+}
+
+// Handles the HTTP request to delete a promotion
+func (h *promotionHandler) DeletePromotionsID(w http.ResponseWriter, r *http.Request) {
+	path := r.URL.Path
+	path = strings.Trim(path, "/")
+	parts := strings.SplitN(path, "/", 2)
+	if len(parts) != 2 {
+		err := errors.New("URL length")
+		SendError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := h.promotionService.DeletePromotion(parts[1]); err != nil {
+		SendError(w, promotionErrorStatus(err), err)
+		return
+	}
+	SendSucces(w, http.StatusOK, "Promotion deleted")
+}
+
+// Maps a promotion error to its HTTP status: 404 for an unknown promotion, 400 otherwise
+func promotionErrorStatus(err error) int {
+	if errors.Is(err, service.ErrPromotionNotFound) {
+		return http.StatusNotFound
+	}
+	return http.StatusBadRequest
+}
@@ -0,0 +1,103 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"hot-coffee/internal/service"
+)
+
+// How often an idle event stream sends a comment to keep the connection open
+const streamHeartbeat = 20 * time.Second
+
+type QueueHandler interface {
+	GetQueue(w http.ResponseWriter, r *http.Request)
+	StreamQueue(w http.ResponseWriter, r *http.Request)
+}
+type queueHandler struct {
+	orderService service.OrderService
+}
+
+// Initializes and returns a new instance of queueHandler with the provided service
+func NewQueueHandler(orderService service.OrderService) QueueHandler {
+	return &queueHandler{orderService: orderService}
+}
+
+// Handles the HTTP request for the orders waiting at the bar, in preparation order
+func (h queueHandler) GetQueue(w http.ResponseWriter, r *http.Request) {
+	queue, err := h.orderService.Queue()
+	if err != nil {
+		SendError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(queue)
+	if err != nil {
+		SendError(w, http.StatusInternalServerError, err)
+		return
+	}
+}
+
+// Streams the queue as Server-Sent Events: a "queue" event with the current queue first,
+// then an event for every change to an order until the client disconnects
+func (h queueHandler) StreamQueue(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		SendError(w, http.StatusInternalServerError, errors.New("Streaming is not supported"))
+		return
+	}
+	// Subscribe before reading the queue so no change between the two is missed
+	events, unsubscribe := h.orderService.Subscribe()
+	defer unsubscribe()
+	queue, err := h.orderService.Queue()
+	if err != nil {
+		SendError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	if err := writeEvent(w, "queue", queue); err != nil {
+		return
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event, open := <-events:
+			// A closed channel means the client fell behind; it reconnects and gets the queue again
+			if !open {
+				return
+			}
+			if err := writeEvent(w, event.Type, event); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
+
+// Writes one Server-Sent Event with the data encoded as JSON
+func writeEvent(w http.ResponseWriter, name string, data any) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		slog.Error("Failed to encode event", slog.String("ERROR", err.Error()))
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", name, payload)
+	return err
+}
@@ -0,0 +1,69 @@
+package handler
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"hot-coffee/models"
+)
+
+// Reads the next Server-Sent Event from a stream, skipping comments, and returns its name and data
+func readEvent(t *testing.T, stream *bufio.Reader) (string, string) {
+	t.Helper()
+	var name, data string
+	for {
+		line, err := stream.ReadString('\n')
+		if err != nil {
+			t.Fatalf("reading the event stream: %v", err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case line == "" && name != "":
+			return name, data
+		case strings.HasPrefix(line, "event: "):
+			name = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+}
+
+// The stream starts with the current queue and then reports every change to an order
+func TestStreamQueue(t *testing.T) {
+	s := newTestServer(t)
+	placed := s.placeOrders(t, 1)[0]
+
+	response, err := s.Client().Get(s.URL + "/queue/stream")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+	if contentType := response.Header.Get("Content-Type"); response.StatusCode != http.StatusOK || contentType != "text/event-stream" {
+		t.Fatalf("GET /queue/stream: %d %s", response.StatusCode, contentType)
+	}
+	stream := bufio.NewReader(response.Body)
+
+	name, data := readEvent(t, stream)
+	var queue []models.Order
+	if err := json.Unmarshal([]byte(data), &queue); name != "queue" || err != nil || len(queue) != 1 || queue[0].ID != placed.ID {
+		t.Fatalf("first event is %s %s, want the queue with order %s", name, data, placed.ID)
+	}
+
+	body := models.PaymentRequest{Tenders: []models.Payment{{Method: models.TenderCard, Amount: placed.Total}}}
+	if status, data := s.do(t, http.MethodPost, "/orders/"+placed.ID+"/payments", body); status != http.StatusCreated {
+		t.Fatalf("paying: %d %s", status, data)
+	}
+	if status, data := s.do(t, http.MethodPost, "/orders/"+placed.ID+"/close", nil); status != http.StatusOK {
+		t.Fatalf("closing: %d %s", status, data)
+	}
+	for _, want := range []string{models.EventOrderPaid, models.EventOrderClosed} {
+		name, data := readEvent(t, stream)
+		var event models.OrderEvent
+		if err := json.Unmarshal([]byte(data), &event); name != want || err != nil || event.Type != want || event.Order.ID != placed.ID {
+			t.Errorf("got event %s %s, want %s for order %s", name, data, want, placed.ID)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"hot-coffee/internal/service"
+	"hot-coffee/models"
+)
+
+type TaxHandler interface {
+	GetRates(w http.ResponseWriter, r *http.Request)
+	PostRates(w http.ResponseWriter, r *http.Request)
+	PutRatesID(w http.ResponseWriter, r *http.Request)
+	DeleteRatesID(w http.ResponseWriter, r *http.Request)
+}
+
+type taxHandler struct {
+	taxService service.TaxService
+}
+
+// Initializes and returns a new instance of taxHandler with the provided service
+func NewTaxHandler(taxService service.TaxService) TaxHandler {
+	return &taxHandler{taxService: taxService}
+}
+
+// Handles the HTTP request to list the tax rates
+func (h *taxHandler) GetRates(w http.ResponseWriter, r *http.Request) {
+	rates, err := h.taxService.ListRates()
+	if err != nil {
+		SendError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(rates)
+	if err != nil {
+		SendError(w, http.StatusInternalServerError, err)
+		return
+	}
+}
+
+// Handles the HTTP request to add a tax rate
+func (h *taxHandler) PostRates(w http.ResponseWriter, r *http.Request) {
+	if err := CheckContentType(r); err != nil {
+		SendError(w, http.StatusBadRequest, err)
+		return
+	}
+	body := models.TaxRate{}
+	err := json.NewDecoder(r.Body).Decode(&body)
+	if err != nil {
+		SendError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := h.taxService.CreateRate(body); err != nil {
+		SendError(w, http.StatusBadRequest, err)
+		return
+	}
+	SendSucces(w, http.StatusCreated, "Tax rate added")
+}
+
+// Handles the HTTP request to replace a tax rate
+func (h *taxHandler) PutRatesID(w http.ResponseWriter, r *http.Request) {
+	if err := CheckContentType(r); err != nil {
+		SendError(w, http.StatusBadRequest, err)
+		return
+	}
+	body := models.TaxRate{}
+	err := json.NewDecoder(r.Body).Decode(&body)
+	if err != nil {
+		SendError(w, http.StatusBadRequest, err)
+		return
+	}
+	path := r.URL.Path
+	path = strings.Trim(path, "/")
+	parts := strings.SplitN(path, "/", 3)
+	if len(parts) != 3 {
+		err := errors.New("URL length")
+		SendError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := h.taxService.UpdateRate(parts[2], body); err != nil {
+		SendError(w, taxErrorStatus(err), err)
+		return
+	}
+	SendSucces(w, http.StatusOK, "Tax rate updated")
+}
+
+// Handles the HTTP request to delete a tax rate
+func (h *taxHandler) DeleteRatesID(w http.ResponseWriter, r *http.Request) {
+	path := r.URL.Path
+	path = strings.Trim(path, "/")
+	parts := strings.SplitN(path, "/", 3)
+	if len(parts) != 3 {
+		err := errors.New("URL length")
+		SendError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := h.taxService.DeleteRate(parts[2]); err != nil {
+		SendError(w, taxErrorStatus(err), err)
+		return
+	}
+	SendSucces(w, http.StatusOK, "Tax rate deleted")
+}
+
+// Maps a tax error to its HTTP status: 404 for an unknown tax rate, 400 otherwise
+func taxErrorStatus(err error) int {
+	if errors.Is(err, service.ErrTaxRateNotFound) {
+		return http.StatusNotFound
+	}
+	return http.StatusBadRequest
+}
@@ -2,15 +2,24 @@ package service
 
 import (
 	"errors"
+	"fmt"
 	"sort"
 
 	"hot-coffee/internal/dal"
 	"hot-coffee/models"
 )
 
+// Ways the reports can group the items sold
+const (
+	GroupByProduct = "product"
+	GroupByVariant = "variant"
+)
+
 type AggregationsService interface {
-	ServiceTotalSales() (float64, error)
-	ServicePopularItems() (error, []models.Popular)
+	ServiceTotalSales() (models.Total, error)
+	ServiceSalesBreakdown(groupBy string) ([]models.SalesGroup, error)
+	ServiceTaxReport(from string, to string) (models.TaxReport, error)
+	ServicePopularItems(groupBy string) (error, []models.Popular)
 }
 
 type aggregationsService struct {
@@ -22,67 +31,224 @@ func NewAggregationsService(aggregationsRepo dal.AggregationsRepository) Aggrega
 	return &aggregationsService{aggregationsRepo: aggregationsRepo}
 }
 
-// Calculates the total sales from all orders by summing the cost of each order
-func (s *aggregationsService) ServiceTotalSales() (float64, error) {
-	err, allorders := s.AllOrders()
+// Sums the totals, discounts, tax, tips and payments per method of the orders that count as sales
+func (s *aggregationsService) ServiceTotalSales() (models.Total, error) {
+	unlock := s.aggregationsRepo.RLock(dal.OrdersFile, dal.MenuItemFile)
+	defer unlock()
+	err, orders := s.SalesOrders()
 	if err != nil {
-		return 0, err
+		return models.Total{}, err
+	}
+	total := models.Total{Payments: []models.PaymentTotal{}}
+	methods := make(map[string]*models.PaymentTotal)
+	for _, order := range orders {
+		total.TotalSales += order.Total
+		total.TotalDiscounts += order.Discount
+		total.TotalTax += order.Tax
+		total.TotalTips += order.Tips
+		for _, payment := range order.Payments {
+			method, exists := methods[payment.Method]
+			if !exists {
+				method = &models.PaymentTotal{Method: payment.Method}
+				methods[payment.Method] = method
+			}
+			method.Payments++
+			method.Amount = roundMoney(method.Amount + payment.Amount)
+			method.Tips = roundMoney(method.Tips + payment.Tip)
+		}
 	}
+	total.TotalSales = roundMoney(total.TotalSales)
+	total.TotalDiscounts = roundMoney(total.TotalDiscounts)
+	total.TotalTax = roundMoney(total.TotalTax)
+	total.TotalTips = roundMoney(total.TotalTips)
+	for _, method := range methods {
+		total.Payments = append(total.Payments, *method)
+	}
+	sort.Slice(total.Payments, func(i, j int) bool { return total.Payments[i].Method < total.Payments[j].Method })
+	return total, nil
+}
 
-	err, total := s.TotalMenu(allorders)
+// Sums the quantities, discounts and discounted line totals sold per product, or per variant
+// of each product, sorted by product and variant
+func (s *aggregationsService) ServiceSalesBreakdown(groupBy string) ([]models.SalesGroup, error) {
+	if err := CheckGroupBy(groupBy); err != nil {
+		return nil, err
+	}
+	unlock := s.aggregationsRepo.RLock(dal.OrdersFile, dal.MenuItemFile)
+	defer unlock()
+	err, orders := s.SalesOrders()
 	if err != nil {
-		return 0, err
+		return nil, err
+	}
+	groups := make(map[[2]string]*models.SalesGroup)
+	for _, order := range orders {
+		for _, item := range order.Items {
+			key := groupKey(item, groupBy)
+			group, exists := groups[key]
+			if !exists {
+				group = &models.SalesGroup{ProductID: key[0], Variant: key[1]}
+				groups[key] = group
+			}
+			group.Quantity += item.Quantity
+			group.Sales = roundMoney(group.Sales + item.LineTotal - item.Discount)
+			group.Discounts = roundMoney(group.Discounts + item.Discount)
+		}
 	}
+	result := make([]models.SalesGroup, 0, len(groups))
+	for _, group := range groups {
+		result = append(result, *group)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].ProductID != result[j].ProductID {
+			return result[i].ProductID < result[j].ProductID
+		}
+		return result[i].Variant < result[j].Variant
+	})
+	return result, nil
+}
 
-	return total, nil
+// Sums the tax charged per tax rate on the orders created in a period, as dates or times in the
+// forms accepted by the order filters, with the sales excluding and including the tax; empty bounds
+// leave the period open. Sales not taxed are reported under an empty rate ID.
+func (s *aggregationsService) ServiceTaxReport(from string, to string) (models.TaxReport, error) {
+	report := models.TaxReport{From: from, To: to, Rates: []models.TaxSummary{}}
+	fromTime, err := parseTimeBound(from, false)
+	if err != nil {
+		return report, err
+	}
+	toTime, err := parseTimeBound(to, true)
+	if err != nil {
+		return report, err
+	}
+	unlock := s.aggregationsRepo.RLock(dal.OrdersFile, dal.MenuItemFile)
+	defer unlock()
+	err, orders := s.SalesOrders()
+	if err != nil {
+		return report, err
+	}
+	type rateVersion struct {
+		id        string
+		rate      float64
+		inclusive bool
+	}
+	summaries := make(map[rateVersion]*models.TaxSummary)
+	add := func(key rateVersion, name string, net, tax float64) {
+		summary, exists := summaries[key]
+		if !exists {
+			summary = &models.TaxSummary{TaxRateID: key.id, Name: name, Rate: key.rate, Inclusive: key.inclusive}
+			summaries[key] = summary
+		}
+		summary.Net = roundMoney(summary.Net + net)
+		summary.Tax = roundMoney(summary.Tax + tax)
+		summary.Gross = roundMoney(summary.Net + summary.Tax)
+	}
+	for _, order := range orders {
+		if !withinTimeRange(order.CreatedAt, fromTime, toTime) {
+			continue
+		}
+		for _, applied := range order.Taxes {
+			net := applied.Taxable
+			if applied.Inclusive {
+				net -= applied.Amount
+			}
+			add(rateVersion{applied.TaxRateID, applied.Rate, applied.Inclusive}, applied.Name, net, applied.Amount)
+		}
+		untaxed := 0.0
+		for _, item := range order.Items {
+			if item.TaxRateID == "" {
+				untaxed += item.LineTotal - item.Discount
+			}
+		}
+		if untaxed > 0 {
+			add(rateVersion{}, "Untaxed", untaxed, 0)
+		}
+	}
+	for _, summary := range summaries {
+		report.Rates = append(report.Rates, *summary)
+		report.NetSales += summary.Net
+		report.Tax += summary.Tax
+	}
+	report.NetSales = roundMoney(report.NetSales)
+	report.Tax = roundMoney(report.Tax)
+	report.GrossSales = roundMoney(report.NetSales + report.Tax)
+	sort.Slice(report.Rates, func(i, j int) bool {
+		if report.Rates[i].TaxRateID != report.Rates[j].TaxRateID {
+			return report.Rates[i].TaxRateID < report.Rates[j].TaxRateID
+		}
+		return report.Rates[i].Rate < report.Rates[j].Rate
+	})
+	return report, nil
 }
 
-// Aggregates quantities of each product ordered across all orders and returns a map of product quantities
-func (s *aggregationsService) AllOrders() (error, map[string]int) {
+// Returns the orders that count as sales, that is all but the cancelled and refunded ones; orders created
+// before prices were captured are priced at today's menu prices
+func (s *aggregationsService) SalesOrders() (error, []models.Order) {
 	orders, err := s.aggregationsRepo.ReadJSONOrder()
 	if err != nil {
 		return err, nil
 	}
-	allorders := make(map[string]int)
-	for _, oneOrder := range orders {
-		for _, orderItem := range oneOrder.Items {
-			allorders[orderItem.ProductID] += orderItem.Quantity
+	err, prices := s.MenuPrices()
+	if err != nil {
+		return err, nil
+	}
+	result := []models.Order{}
+	for _, order := range orders {
+		if !countsAsSale(order) {
+			continue
+		}
+		if !hasCapturedPrices(order) {
+			for i, item := range order.Items {
+				order.Items[i].UnitPrice = prices[item.ProductID]
+			}
+			computeTotals(&order)
 		}
+		result = append(result, order)
 	}
-	return nil, allorders
+	return nil, result
 }
 
-// Calculates the total revenue by multiplying product quantities with their prices from the menu
-func (s *aggregationsService) TotalMenu(allOrders map[string]int) (error, float64) {
+// Reports whether an order counts as a sale, that is it was neither cancelled nor refunded
+func countsAsSale(order models.Order) bool {
+	return order.Status != models.StatusCancelled && order.Status != models.StatusRefunded
+}
+
+// Returns the current price of every menu item by product ID
+func (s *aggregationsService) MenuPrices() (error, map[string]float64) {
 	menu, err := s.aggregationsRepo.ReadJSONMenu()
 	if err != nil {
-		return err, 0
+		return err, nil
 	}
-	total := 0.0
+	prices := make(map[string]float64, len(menu))
 	for _, item := range menu {
-		quantity, exists := allOrders[item.ID]
-		if exists {
-			total += float64(quantity) * item.Price
-		}
+		prices[item.ID] = item.Price
 	}
-	return nil, total
+	return nil, prices
 }
 
-// Finds and returns a sorted list of popular items based on quantities ordered
-func (s *aggregationsService) ServicePopularItems() (error, []models.Popular) {
+// Finds and returns a sorted list of popular items based on quantities sold, per product
+// or per variant of each product
+func (s *aggregationsService) ServicePopularItems(groupBy string) (error, []models.Popular) {
+	if err := CheckGroupBy(groupBy); err != nil {
+		return err, nil
+	}
+	unlock := s.aggregationsRepo.RLock(dal.OrdersFile)
+	defer unlock()
 	orders, err := s.aggregationsRepo.ReadJSONOrder()
 	if err != nil {
 		return err, nil
 	}
 	result := []models.Popular{}
-	tempMap := make(map[string]int)
+	tempMap := make(map[[2]string]int)
 	for _, oneOrder := range orders {
+		if !countsAsSale(oneOrder) {
+			continue
+		}
 		for _, item := range oneOrder.Items {
-			tempMap[item.ProductID] += item.Quantity
+			tempMap[groupKey(item, groupBy)] += item.Quantity
 		}
 	}
-	for id, quantity := range tempMap {
-		result = append(result, models.Popular{PopularSales: id, Quantity: quantity})
+	for key, quantity := range tempMap {
+		result = append(result, models.Popular{PopularSales: key[0], Variant: key[1], Quantity: quantity})
 	}
 	sort.Slice(result, func(i, j int) bool {
 		return result[i].Quantity > result[j].Quantity
@@ -93,3 +259,19 @@ func (s *aggregationsService) ServicePopularItems() (error, []models.Popular) {
 
 	return nil, result
 }
+
+// CheckGroupBy checks that groupBy names a supported grouping of the reports
+func CheckGroupBy(groupBy string) error {
+	if groupBy != GroupByProduct && groupBy != GroupByVariant {
+		return fmt.Errorf("Unknown grouping %q, expected %s or %s", groupBy, GroupByProduct, GroupByVariant)
+	}
+	return nil
+}
+
+// Returns the product ID and, when grouping by variant, the variant an order item is counted under
+func groupKey(item models.OrderItem, groupBy string) [2]string {
+	if groupBy == GroupByVariant {
+		return [2]string{item.ProductID, item.Variant}
+	}
+	return [2]string{item.ProductID, ""}
+}
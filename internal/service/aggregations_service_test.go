@@ -0,0 +1,45 @@
+package service
+
+import (
+	"testing"
+
+	"hot-coffee/internal/dal"
+	"hot-coffee/models"
+)
+
+// Cancelled and refunded orders count neither in the sales nor in the popular items
+func TestReportsLeaveOutCancelledAndRefunded(t *testing.T) {
+	store, _ := newTestStore(t)
+	item := func(quantity int) []models.OrderItem {
+		return []models.OrderItem{{ProductID: "latte", Quantity: quantity, UnitPrice: 3.5}}
+	}
+	orders := []models.Order{
+		{ID: "1", CustomerName: "Ada", Status: models.StatusClosed, Items: item(1), Subtotal: 3.5, Total: 3.5, Paid: 3.5},
+		{ID: "2", CustomerName: "Ada", Status: models.StatusCancelled, Items: item(5), Subtotal: 17.5, Total: 17.5},
+		{ID: "3", CustomerName: "Ada", Status: models.StatusRefunded, Items: item(7), Subtotal: 24.5, Total: 24.5, Paid: 24.5},
+		{ID: "4", CustomerName: "Ada", Status: models.StatusOpen, Items: item(2), Subtotal: 7, Total: 7},
+	}
+	tx := store.Begin()
+	if err := tx.StageOrders(orders); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	s := NewAggregationsService(dal.NewAggregationsRepository(store, dal.NewFileLocks()))
+
+	err, popular := s.ServicePopularItems(GroupByProduct)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(popular) != 1 || popular[0].PopularSales != "latte" || popular[0].Quantity != 3 {
+		t.Errorf("popular items are %+v, want 3 lattes", popular)
+	}
+	total, err := s.ServiceTotalSales()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total.TotalSales != 10.5 {
+		t.Errorf("total sales are %.2f, want 10.50", total.TotalSales)
+	}
+}
@@ -0,0 +1,134 @@
+package service
+
+import (
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"hot-coffee/internal/dal"
+	"hot-coffee/models"
+)
+
+// ErrSnapshotNotFound is returned when restoring a snapshot that does not exist
+var ErrSnapshotNotFound = dal.ErrSnapshotNotFound
+
+// BackupService takes, lists and restores versioned snapshots of the data
+type BackupService interface {
+	ServiceListBackups() ([]models.Snapshot, error)
+	ServiceCreateBackup() (models.Snapshot, error)
+	ServiceRestore(id string) error
+	StartScheduler(interval time.Duration)
+}
+
+type backupService struct {
+	backupRepo  dal.BackupRepository
+	idempotency IdempotencyService // Reloaded after a restore, which replaces its stored responses.
+	keepPerDay  int
+	keepDays    int
+	mu          sync.Mutex // Serializes snapshots, pruning and restores.
+	lastVersion uint64     // Data version captured by the last snapshot.
+	hasSnapshot bool
+}
+
+// Initializes and returns a new instance of backupService keeping at most keepPerDay
+// snapshots for each of the keepDays most recent days
+func NewBackupService(backupRepo dal.BackupRepository, idempotency IdempotencyService, keepPerDay, keepDays int) BackupService {
+	return &backupService{backupRepo: backupRepo, idempotency: idempotency, keepPerDay: keepPerDay, keepDays: keepDays}
+}
+
+// Returns all snapshots, newest first
+func (s *backupService) ServiceListBackups() ([]models.Snapshot, error) {
+	return s.backupRepo.ListSnapshots()
+}
+
+// Takes a snapshot of the current data and applies the retention policy
+func (s *backupService) ServiceCreateBackup() (models.Snapshot, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.snapshot()
+}
+
+// Restores a snapshot of all data files together, after saving the
+// current data in a new snapshot so the restore itself can be undone
+func (s *backupService) ServiceRestore(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	unlock := s.backupRepo.Lock(dal.OrdersFile, dal.MenuItemFile, dal.InventoryitemFile, dal.CustomersFile, dal.LoyaltyRulesFile, dal.LoyaltyLedgerFile, dal.PromotionsFile, dal.TaxRatesFile)
+	defer unlock()
+
+	snapshots, err := s.backupRepo.ListSnapshots()
+	if err != nil {
+		return err
+	}
+	if !slices.ContainsFunc(snapshots, func(snapshot models.Snapshot) bool { return snapshot.ID == id }) {
+		return ErrSnapshotNotFound
+	}
+	safety, err := s.backupRepo.CreateSnapshot()
+	if err != nil {
+		return err
+	}
+	if err := s.idempotency.Reload(func() error { return s.backupRepo.RestoreSnapshot(id) }); err != nil {
+		return err
+	}
+	slog.Info("Snapshot restored", slog.String("snapshot", id), slog.String("previous_data", safety.ID))
+	return nil
+}
+
+// Starts a background loop taking a snapshot every interval, skipped when the data
+// has not changed since the last snapshot
+func (s *backupService) StartScheduler(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			s.mu.Lock()
+			if !s.hasSnapshot || s.backupRepo.DataVersion() != s.lastVersion {
+				if _, err := s.snapshot(); err != nil {
+					slog.Error("Scheduled backup failed", slog.String("ERROR", err.Error()))
+				}
+			}
+			s.mu.Unlock()
+		}
+	}()
+}
+
+// Takes a consistent snapshot and prunes old ones; the caller holds s.mu
+func (s *backupService) snapshot() (models.Snapshot, error) {
+	unlock := s.backupRepo.RLock(dal.OrdersFile, dal.MenuItemFile, dal.InventoryitemFile, dal.CustomersFile, dal.LoyaltyRulesFile, dal.LoyaltyLedgerFile, dal.PromotionsFile, dal.TaxRatesFile)
+	version := s.backupRepo.DataVersion()
+	snapshot, err := s.backupRepo.CreateSnapshot()
+	unlock()
+	if err != nil {
+		return snapshot, err
+	}
+	s.lastVersion, s.hasSnapshot = version, true
+	slog.Info("Snapshot created", slog.String("snapshot", snapshot.ID))
+	return snapshot, s.prune()
+}
+
+// Deletes the snapshots outside the retention policy: only the keepDays most recent
+// days that have snapshots are kept, and within each of them the keepPerDay newest
+func (s *backupService) prune() error {
+	snapshots, err := s.backupRepo.ListSnapshots()
+	if err != nil {
+		return err
+	}
+	perDay := make(map[string]int)
+	for _, snapshot := range snapshots {
+		day := snapshot.ID[:8] // Snapshot IDs start with the UTC date as YYYYMMDD.
+		if _, seen := perDay[day]; !seen && len(perDay) >= s.keepDays {
+			if err := s.backupRepo.DeleteSnapshot(snapshot.ID); err != nil {
+				return err
+			}
+			continue
+		}
+		perDay[day]++
+		if perDay[day] > s.keepPerDay {
+			if err := s.backupRepo.DeleteSnapshot(snapshot.ID); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
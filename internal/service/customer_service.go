@@ -0,0 +1,256 @@
+package service
+
+import (
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"hot-coffee/internal/dal"
+	"hot-coffee/models"
+)
+
+// ErrCustomerNotFound is returned for a customer ID that does not name an existing customer
+var ErrCustomerNotFound = errors.New("Customer not found")
+
+// CustomerService registers customers and reports the totals of their orders
+type CustomerService interface {
+	CreateCustomer(body models.Customer) (models.Customer, error)
+	ListCustomers(query models.CustomerQuery) ([]models.CustomerSummary, error)
+	GetCustomer(id string) (models.CustomerSummary, error)
+	UpdateCustomer(id string, body models.Customer) (models.Customer, error)
+	DeleteCustomer(id string) error
+}
+
+type customerService struct {
+	customerRepo dal.CustomerRepository
+}
+
+// Initializes and returns a new instance of customerService with the provided repository
+func NewCustomerService(customerRepo dal.CustomerRepository) CustomerService {
+	return &customerService{customerRepo: customerRepo}
+}
+
+// Registers a new customer under the next free ID; the phone and email, when given,
+// must not belong to another customer
+func (s *customerService) CreateCustomer(body models.Customer) (models.Customer, error) {
+	unlock := s.customerRepo.Lock(dal.CustomersFile)
+	defer unlock()
+	customer, err := normalizeCustomer(body)
+	if err != nil {
+		return customer, err
+	}
+	customers, err := s.customerRepo.ReadCustomers()
+	if err != nil {
+		return customer, err
+	}
+	if err := checkContactsFree(customers, customer); err != nil {
+		return customer, err
+	}
+	customer.ID = nextCustomerID(customers)
+	customer.CreatedAt = time.Now().Format(models.TimeLayout)
+	if err := s.customerRepo.WriteCustomers(append(customers, customer)); err != nil {
+		return customer, err
+	}
+	return customer, nil
+}
+
+// Returns the customers matching the query with the totals of their orders, by ID
+func (s *customerService) ListCustomers(query models.CustomerQuery) ([]models.CustomerSummary, error) {
+	unlock := s.customerRepo.RLock(dal.CustomersFile, dal.OrdersFile)
+	defer unlock()
+	customers, err := s.customerRepo.ReadCustomers()
+	if err != nil {
+		return nil, err
+	}
+	orders, err := s.customerRepo.ReadOrders()
+	if err != nil {
+		return nil, err
+	}
+	name := normalizeName(query.Name)
+	phone := normalizePhone(query.Phone)
+	email := normalizeEmail(query.Email)
+	result := []models.CustomerSummary{}
+	for _, customer := range customers {
+		if name != "" && !strings.EqualFold(customer.Name, name) ||
+			phone != "" && customer.Phone != phone ||
+			email != "" && customer.Email != email {
+			continue
+		}
+		result = append(result, summarizeCustomer(customer, orders))
+	}
+	slices.SortFunc(result, func(a, b models.CustomerSummary) int {
+		return compareIDs(a.ID, b.ID)
+	})
+	return result, nil
+}
+
+// Returns one customer with the totals of their orders
+func (s *customerService) GetCustomer(id string) (models.CustomerSummary, error) {
+	unlock := s.customerRepo.RLock(dal.CustomersFile, dal.OrdersFile)
+	defer unlock()
+	customer, exists := s.customerRepo.FindCustomer(id)
+	if !exists {
+		return models.CustomerSummary{}, ErrCustomerNotFound
+	}
+	orders, err := s.customerRepo.ReadOrders()
+	if err != nil {
+		return models.CustomerSummary{}, err
+	}
+	return summarizeCustomer(customer, orders), nil
+}
+
+// Changes the name, phone or email of a customer; fields left empty keep their value
+func (s *customerService) UpdateCustomer(id string, body models.Customer) (models.Customer, error) {
+	unlock := s.customerRepo.Lock(dal.CustomersFile)
+	defer unlock()
+	customers, err := s.customerRepo.ReadCustomers()
+	if err != nil {
+		return models.Customer{}, err
+	}
+	i := slices.IndexFunc(customers, func(c models.Customer) bool { return c.ID == id })
+	if i < 0 {
+		return models.Customer{}, ErrCustomerNotFound
+	}
+	edited := customers[i]
+	if strings.TrimSpace(body.Name) != "" {
+		edited.Name = body.Name
+	}
+	if strings.TrimSpace(body.Phone) != "" {
+		edited.Phone = body.Phone
+	}
+	if strings.TrimSpace(body.Email) != "" {
+		edited.Email = body.Email
+	}
+	edited, err = normalizeCustomer(edited)
+	if err != nil {
+		return edited, err
+	}
+	others := slices.Delete(slices.Clone(customers), i, i+1)
+	if err := checkContactsFree(others, edited); err != nil {
+		return edited, err
+	}
+	customers[i] = edited
+	if err := s.customerRepo.WriteCustomers(customers); err != nil {
+		return edited, err
+	}
+	return edited, nil
+}
+
+// Deletes a customer that has no orders and no loyalty history; those keep a customer on record
+func (s *customerService) DeleteCustomer(id string) error {
+	unlock := s.customerRepo.Lock(dal.CustomersFile, dal.OrdersFile, dal.LoyaltyLedgerFile)
+	defer unlock()
+	customers, err := s.customerRepo.ReadCustomers()
+	if err != nil {
+		return err
+	}
+	i := slices.IndexFunc(customers, func(c models.Customer) bool { return c.ID == id })
+	if i < 0 {
+		return ErrCustomerNotFound
+	}
+	orders, err := s.customerRepo.ReadOrders()
+	if err != nil {
+		return err
+	}
+	if slices.ContainsFunc(orders, func(order models.Order) bool { return order.CustomerID == id }) {
+		return errors.New("Customer has orders and cannot be deleted")
+	}
+	ledger, err := s.customerRepo.ReadLedger()
+	if err != nil {
+		return err
+	}
+	if slices.ContainsFunc(ledger, func(entry models.LoyaltyEntry) bool { return entry.CustomerID == id }) {
+		return errors.New("Customer has loyalty history and cannot be deleted")
+	}
+	return s.customerRepo.WriteCustomers(slices.Delete(customers, i, i+1))
+}
+
+// Adds up the orders linked to a customer: visits and spending count closed orders only
+func summarizeCustomer(customer models.Customer, orders []models.Order) models.CustomerSummary {
+	summary := models.CustomerSummary{Customer: customer}
+	for _, order := range orders {
+		if order.CustomerID != customer.ID {
+			continue
+		}
+		summary.Orders++
+		if order.Status != models.StatusClosed {
+			continue
+		}
+		summary.Visits++
+		summary.TotalSpent += order.Total
+		if order.CreatedAt > summary.LastVisit {
+			summary.LastVisit = order.CreatedAt
+		}
+	}
+	summary.TotalSpent = roundMoney(summary.TotalSpent)
+	return summary
+}
+
+// Validates a customer and brings the name, phone and email into their stored form
+func normalizeCustomer(customer models.Customer) (models.Customer, error) {
+	customer.Name = normalizeName(customer.Name)
+	if customer.Name == "" {
+		return customer, errors.New("Missing customer name")
+	}
+	if customer.Phone != "" {
+		customer.Phone = normalizePhone(customer.Phone)
+		digits := strings.TrimPrefix(customer.Phone, "+")
+		if len(digits) < 7 || len(digits) > 15 || strings.Trim(digits, "0123456789") != "" {
+			return customer, errors.New("Invalid phone number")
+		}
+	}
+	if customer.Email != "" {
+		customer.Email = normalizeEmail(customer.Email)
+		local, domain, found := strings.Cut(customer.Email, "@")
+		if !found || local == "" || !strings.Contains(domain, ".") || strings.ContainsAny(domain, "@ ") || strings.Contains(local, " ") {
+			return customer, errors.New("Invalid email address")
+		}
+	}
+	return customer, nil
+}
+
+// Trims a name and collapses the spaces inside it
+func normalizeName(name string) string {
+	return strings.Join(strings.Fields(name), " ")
+}
+
+// Removes the spaces, dashes, dots and brackets people write phone numbers with
+func normalizePhone(phone string) string {
+	return strings.Map(func(r rune) rune {
+		if strings.ContainsRune(" -.()", r) {
+			return -1
+		}
+		return r
+	}, phone)
+}
+
+// Trims an email address and makes it lower case
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// Checks that no other customer has the phone or email of customer
+func checkContactsFree(others []models.Customer, customer models.Customer) error {
+	for _, other := range others {
+		if customer.Phone != "" && other.Phone == customer.Phone {
+			return errors.New("A customer with this phone number already exists")
+		}
+		if customer.Email != "" && other.Email == customer.Email {
+			return errors.New("A customer with this email address already exists")
+		}
+	}
+	return nil
+}
+
+// Returns the ID after the highest numeric customer ID
+func nextCustomerID(customers []models.Customer) string {
+	last := 0
+	for _, customer := range customers {
+		if n, err := strconv.Atoi(customer.ID); err == nil && n > last {
+			last = n
+		}
+	}
+	return strconv.Itoa(last + 1)
+}
@@ -0,0 +1,114 @@
+package service
+
+import (
+	"testing"
+
+	"hot-coffee/internal/dal"
+	"hot-coffee/models"
+)
+
+func TestNormalizeCustomer(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      models.Customer
+		want    models.Customer
+		wantErr bool
+	}{
+		{name: "name only", in: models.Customer{Name: "  Jake   Peralta "}, want: models.Customer{Name: "Jake Peralta"}},
+		{name: "phone punctuation", in: models.Customer{Name: "Jake", Phone: "+1 (555) 010-99.00"}, want: models.Customer{Name: "Jake", Phone: "+15550109900"}},
+		{name: "email case", in: models.Customer{Name: "Jake", Email: " Jake@Example.COM "}, want: models.Customer{Name: "Jake", Email: "jake@example.com"}},
+		{name: "missing name", in: models.Customer{Name: "   ", Phone: "5550109900"}, wantErr: true},
+		{name: "short phone", in: models.Customer{Name: "Jake", Phone: "555-01"}, wantErr: true},
+		{name: "letters in phone", in: models.Customer{Name: "Jake", Phone: "555-CALL-NOW"}, wantErr: true},
+		{name: "email without domain dot", in: models.Customer{Name: "Jake", Email: "jake@example"}, wantErr: true},
+		{name: "email without local part", in: models.Customer{Name: "Jake", Email: "@example.com"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := normalizeCustomer(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("normalizeCustomer(%+v) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("normalizeCustomer(%+v) = %+v, want %+v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+// Visits and spending count closed orders only, while every linked order counts as an order
+func TestSummarizeCustomer(t *testing.T) {
+	customer := models.Customer{ID: "1", Name: "Jake"}
+	orders := []models.Order{
+		{ID: "1", CustomerID: "1", Status: models.StatusClosed, Total: 3.1, CreatedAt: "2026-10-01T09:00:00Z"},
+		{ID: "2", CustomerID: "1", Status: models.StatusClosed, Total: 4.2, CreatedAt: "2026-10-03T09:00:00Z"},
+		{ID: "3", CustomerID: "1", Status: models.StatusOpen, Total: 7, CreatedAt: "2026-10-05T09:00:00Z"},
+		{ID: "4", CustomerID: "1", Status: models.StatusCancelled, Total: 9, CreatedAt: "2026-10-06T09:00:00Z"},
+		{ID: "5", CustomerID: "2", Status: models.StatusClosed, Total: 50, CreatedAt: "2026-10-07T09:00:00Z"},
+	}
+	got := summarizeCustomer(customer, orders)
+	want := models.CustomerSummary{Customer: customer, Orders: 4, Visits: 2, TotalSpent: 7.3, LastVisit: "2026-10-03T09:00:00Z"}
+	if got != want {
+		t.Errorf("summarizeCustomer() = %+v, want %+v", got, want)
+	}
+}
+
+// Customers are created under increasing IDs, found by their normalized contacts and
+// kept on record once they have orders
+func TestCustomerService(t *testing.T) {
+	store, _ := newTestStore(t)
+	s := NewCustomerService(dal.NewJSONCustomerRepository(store, dal.NewFileLocks()))
+
+	jake, err := s.CreateCustomer(models.Customer{Name: "Jake", Phone: "555 010 9900", Email: "Jake@Example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	amy, err := s.CreateCustomer(models.Customer{Name: "Amy", Phone: "555 010 9901"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if jake.ID != "1" || amy.ID != "2" {
+		t.Errorf("customers got IDs %q and %q, want 1 and 2", jake.ID, amy.ID)
+	}
+	if _, err := s.CreateCustomer(models.Customer{Name: "Jacob", Phone: "555-010-9900"}); err == nil {
+		t.Error("created a customer with a phone number that is taken")
+	}
+	if _, err := s.UpdateCustomer(amy.ID, models.Customer{Email: "JAKE@example.com"}); err == nil {
+		t.Error("gave a customer an email address that is taken")
+	}
+	if _, err := s.UpdateCustomer("9", models.Customer{Name: "Nobody"}); err != ErrCustomerNotFound {
+		t.Errorf("updating a missing customer: %v, want %v", err, ErrCustomerNotFound)
+	}
+
+	found, err := s.ListCustomers(models.CustomerQuery{Email: " jake@EXAMPLE.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 1 || found[0].ID != jake.ID {
+		t.Errorf("looking up by email found %+v, want customer %s", found, jake.ID)
+	}
+
+	tx := store.Begin()
+	if err := tx.StageOrders([]models.Order{{ID: "1", CustomerID: jake.ID, CustomerName: "Jake", Status: models.StatusClosed, Total: 3.5}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	summary, err := s.GetCustomer(jake.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.Visits != 1 || summary.TotalSpent != 3.5 {
+		t.Errorf("customer %s has %d visits and spent %.2f, want 1 and 3.50", jake.ID, summary.Visits, summary.TotalSpent)
+	}
+	if err := s.DeleteCustomer(jake.ID); err == nil {
+		t.Error("deleted a customer that has orders")
+	}
+	if err := s.DeleteCustomer(amy.ID); err != nil {
+		t.Errorf("deleting a customer without orders: %v", err)
+	}
+	if _, err := s.GetCustomer(amy.ID); err != ErrCustomerNotFound {
+		t.Errorf("getting a deleted customer: %v, want %v", err, ErrCustomerNotFound)
+	}
+}
@@ -0,0 +1,65 @@
+package service
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+
+	"hot-coffee/models"
+)
+
+// Events buffered for each subscriber before it is considered too slow
+const subscriberBuffer = 64
+
+// EventBus delivers order events to any number of subscribers. Publishing never blocks:
+// a subscriber that falls behind is dropped and its channel closed, so it can resubscribe
+// and reload the current state.
+type EventBus interface {
+	Publish(eventType string, order models.Order)
+	Subscribe() (events <-chan models.OrderEvent, unsubscribe func())
+}
+
+type eventBus struct {
+	mu          sync.Mutex
+	nextID      int
+	subscribers map[int]chan models.OrderEvent
+}
+
+// Creates an event bus without subscribers
+func NewEventBus() EventBus {
+	return &eventBus{subscribers: make(map[int]chan models.OrderEvent)}
+}
+
+// Sends an event about the order to every subscriber
+func (b *eventBus) Publish(eventType string, order models.Order) {
+	event := models.OrderEvent{Type: eventType, At: time.Now().Format(models.TimeLayout), Order: order}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for id, ch := range b.subscribers {
+		select {
+		case ch <- event:
+		default:
+			slog.Warn("Dropped slow event subscriber", slog.Int("subscriber", id))
+			delete(b.subscribers, id)
+			close(ch)
+		}
+	}
+}
+
+// Returns a channel receiving the events published from now on and a function that stops them
+func (b *eventBus) Subscribe() (<-chan models.OrderEvent, func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	id := b.nextID
+	b.nextID++
+	ch := make(chan models.OrderEvent, subscriberBuffer)
+	b.subscribers[id] = ch
+	return ch, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if _, exists := b.subscribers[id]; exists {
+			delete(b.subscribers, id)
+			close(ch)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package service
+
+import (
+	"slices"
+	"testing"
+
+	"hot-coffee/models"
+)
+
+// Returns the types of the events waiting on a channel without blocking
+func pendingEvents(events <-chan models.OrderEvent) []string {
+	var types []string
+	for {
+		select {
+		case event, open := <-events:
+			if !open {
+				return append(types, "<closed>")
+			}
+			types = append(types, event.Type)
+		default:
+			return types
+		}
+	}
+}
+
+func TestEventBus(t *testing.T) {
+	bus := NewEventBus()
+	first, unsubscribeFirst := bus.Subscribe()
+	bus.Publish(models.EventOrderCreated, models.Order{ID: "1"})
+	second, unsubscribeSecond := bus.Subscribe()
+	defer unsubscribeSecond()
+	bus.Publish(models.EventOrderPaid, models.Order{ID: "1"})
+	unsubscribeFirst()
+	unsubscribeFirst() // Unsubscribing twice is harmless.
+	bus.Publish(models.EventOrderClosed, models.Order{ID: "1"})
+
+	if got, want := pendingEvents(first), []string{models.EventOrderCreated, models.EventOrderPaid, "<closed>"}; !slices.Equal(got, want) {
+		t.Errorf("first subscriber got %v, want %v", got, want)
+	}
+	if got, want := pendingEvents(second), []string{models.EventOrderPaid, models.EventOrderClosed}; !slices.Equal(got, want) {
+		t.Errorf("second subscriber got %v, want %v", got, want)
+	}
+}
+
+// A subscriber that stops reading is dropped once its buffer is full, without blocking the others
+func TestEventBusDropsSlowSubscriber(t *testing.T) {
+	bus := NewEventBus()
+	slow, unsubscribeSlow := bus.Subscribe()
+	fast, unsubscribeFast := bus.Subscribe()
+	defer unsubscribeFast()
+	for i := 0; i <= subscriberBuffer; i++ {
+		bus.Publish(models.EventOrderUpdated, models.Order{ID: "1"})
+		if got := pendingEvents(fast); len(got) != 1 {
+			t.Fatalf("fast subscriber got %v after event %d", got, i)
+		}
+	}
+	got := pendingEvents(slow)
+	if len(got) != subscriberBuffer+1 || got[len(got)-1] != "<closed>" {
+		t.Errorf("slow subscriber got %d events, want %d and a closed channel", len(got), subscriberBuffer)
+	}
+	unsubscribeSlow() // Unsubscribing after being dropped is harmless.
+}
@@ -0,0 +1,148 @@
+package service
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"hot-coffee/internal/dal"
+)
+
+// Names of the supported order ID strategies
+const (
+	IDStrategySequence = "sequence"
+	IDStrategyULID     = "ulid"
+)
+
+// IDGenerator issues order IDs that stay unique across restarts
+type IDGenerator interface {
+	NextID() (string, error)
+}
+
+// Creates the ID generator for the named strategy, initialized from the existing orders
+func NewIDGenerator(strategy string, seqRepo dal.SequenceRepository, orderRepo dal.OrderRepository) (IDGenerator, error) {
+	switch strategy {
+	case IDStrategySequence:
+		return NewSequenceGenerator(seqRepo, orderRepo)
+	case IDStrategyULID:
+		return NewULIDGenerator(), nil
+	default:
+		return nil, fmt.Errorf("Unknown ID strategy: %s", strategy)
+	}
+}
+
+// sequenceGenerator issues increasing numeric IDs and persists the last one before handing it out
+type sequenceGenerator struct {
+	mu      sync.Mutex
+	last    int
+	seqRepo dal.SequenceRepository
+}
+
+// Creates a sequence generator that continues after both the persisted sequence and the
+// highest numeric ID among the existing orders, so IDs never repeat even if the sequence
+// file is lost or older than orders.json
+func NewSequenceGenerator(seqRepo dal.SequenceRepository, orderRepo dal.OrderRepository) (IDGenerator, error) {
+	last, err := seqRepo.ReadSequence()
+	if err != nil {
+		return nil, err
+	}
+	orders, err := orderRepo.ReadJSONOrder()
+	if err != nil {
+		return nil, err
+	}
+	for _, order := range orders {
+		if n, err := strconv.Atoi(order.ID); err == nil && n > last {
+			last = n
+		}
+	}
+	return &sequenceGenerator{last: last, seqRepo: seqRepo}, nil
+}
+
+// Returns the next number in the sequence once it has been persisted
+func (g *sequenceGenerator) NextID() (string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	next := g.last + 1
+	if err := g.seqRepo.WriteSequence(next); err != nil {
+		return "", err
+	}
+	g.last = next
+	return strconv.Itoa(next), nil
+}
+
+// Crockford's base32 alphabet used by ULIDs
+const ulidAlphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// ulidGenerator issues ULIDs: a 48-bit millisecond timestamp followed by 80 random bits,
+// encoded as 26 characters that sort in creation order. IDs created within the same
+// millisecond increment the random part, so they stay sorted as well.
+type ulidGenerator struct {
+	mu      sync.Mutex
+	lastMs  uint64
+	entropy [10]byte
+}
+
+// Creates a generator of time-sortable unique IDs
+func NewULIDGenerator() IDGenerator {
+	return &ulidGenerator{}
+}
+
+// Returns a new ULID
+func (g *ulidGenerator) NextID() (string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	ms := uint64(time.Now().UnixMilli())
+	if ms <= g.lastMs {
+		ms = g.lastMs
+		if !incrementEntropy(&g.entropy) {
+			return "", errors.New("Too many IDs generated in one millisecond")
+		}
+	} else {
+		if _, err := rand.Read(g.entropy[:]); err != nil {
+			return "", err
+		}
+	}
+	g.lastMs = ms
+
+	var raw [16]byte
+	for i := 0; i < 6; i++ {
+		raw[i] = byte(ms >> (40 - 8*i))
+	}
+	copy(raw[6:], g.entropy[:])
+	return encodeULID(raw), nil
+}
+
+// Adds one to the entropy, reporting false on overflow
+func incrementEntropy(entropy *[10]byte) bool {
+	for i := len(entropy) - 1; i >= 0; i-- {
+		entropy[i]++
+		if entropy[i] != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Encodes 128 bits as 26 base32 characters, most significant bits first
+func encodeULID(raw [16]byte) string {
+	out := make([]byte, 26)
+	// 26 characters hold 130 bits, so the value is read as if prefixed by two zero bits
+	for i := 25; i >= 0; i-- {
+		lowest := 5 * (25 - i) // Position of the lowest bit of this character, counted from the right.
+		var v byte
+		for b := 0; b < 5; b++ {
+			pos := lowest + b
+			if pos >= 128 {
+				continue
+			}
+			if raw[15-pos/8]>>(pos%8)&1 == 1 {
+				v |= 1 << b
+			}
+		}
+		out[i] = ulidAlphabet[v]
+	}
+	return string(out)
+}
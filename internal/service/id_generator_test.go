@@ -0,0 +1,121 @@
+package service
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"hot-coffee/internal/dal"
+	"hot-coffee/models"
+)
+
+// The sequence continues after both the persisted value and the highest numeric order ID
+func TestSequenceGenerator(t *testing.T) {
+	tests := []struct {
+		name      string
+		persisted int
+		orderIDs  []string
+		want      []string
+	}{
+		{"fresh", 0, nil, []string{"1", "2"}},
+		{"persisted ahead of the orders", 7, []string{"3"}, []string{"8", "9"}},
+		{"orders ahead of the persisted value", 2, []string{"5", "abc"}, []string{"6", "7"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			store, cfg := newTestStore(t)
+			seqRepo := dal.NewJSONSequenceRepository(cfg)
+			if err := seqRepo.WriteSequence(test.persisted); err != nil {
+				t.Fatal(err)
+			}
+			var orders []models.Order
+			for _, id := range test.orderIDs {
+				orders = append(orders, models.Order{ID: id, CustomerName: "Ada", Status: models.StatusClosed})
+			}
+			tx := store.Begin()
+			if err := tx.StageOrders(orders); err != nil {
+				t.Fatal(err)
+			}
+			if err := tx.Commit(); err != nil {
+				t.Fatal(err)
+			}
+			gen, err := NewIDGenerator(IDStrategySequence, seqRepo, dal.NewJSONOrderRepository(store, dal.NewFileLocks()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, want := range test.want {
+				if got, err := gen.NextID(); err != nil || got != want {
+					t.Fatalf("NextID() = %q, %v, want %q", got, err, want)
+				}
+			}
+			// The last ID is persisted before it is handed out
+			want := test.want[len(test.want)-1]
+			if last, err := seqRepo.ReadSequence(); err != nil || strconv.Itoa(last) != want {
+				t.Errorf("persisted sequence is %d, %v, want %s", last, err, want)
+			}
+		})
+	}
+}
+
+// ULIDs are 26 Crockford base32 characters that sort in the order they were issued
+func TestULIDGenerator(t *testing.T) {
+	gen := NewULIDGenerator()
+	ids := make([]string, 1000)
+	seen := make(map[string]bool, len(ids))
+	for i := range ids {
+		id, err := gen.NextID()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(id) != 26 || strings.Trim(id, ulidAlphabet) != "" {
+			t.Fatalf("malformed ULID %q", id)
+		}
+		if seen[id] {
+			t.Fatalf("duplicate ULID %q", id)
+		}
+		seen[id] = true
+		ids[i] = id
+	}
+	if !sort.StringsAreSorted(ids) {
+		t.Error("ULIDs are not sorted in the order they were issued")
+	}
+}
+
+func TestEncodeULID(t *testing.T) {
+	var max [16]byte
+	for i := range max {
+		max[i] = 0xff
+	}
+	tests := []struct {
+		raw  [16]byte
+		want string
+	}{
+		{[16]byte{}, "00000000000000000000000000"},
+		{[16]byte{15: 1}, "00000000000000000000000001"},
+		{[16]byte{15: 32}, "00000000000000000000000010"},
+		{max, "7ZZZZZZZZZZZZZZZZZZZZZZZZZ"},
+	}
+	for _, test := range tests {
+		if got := encodeULID(test.raw); got != test.want {
+			t.Errorf("encodeULID(%x) = %s, want %s", test.raw, got, test.want)
+		}
+	}
+}
+
+func TestIncrementEntropy(t *testing.T) {
+	entropy := [10]byte{9: 0xff}
+	if !incrementEntropy(&entropy) || entropy != [10]byte{8: 1} {
+		t.Errorf("carry gave %x", entropy)
+	}
+	full := [10]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}
+	if incrementEntropy(&full) {
+		t.Error("overflow not reported")
+	}
+}
+
+func TestNewIDGeneratorUnknownStrategy(t *testing.T) {
+	if _, err := NewIDGenerator("uuid", nil, nil); err == nil {
+		t.Error("unknown strategy accepted")
+	}
+}
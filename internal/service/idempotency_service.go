@@ -0,0 +1,131 @@
+package service
+
+import (
+	"errors"
+	"slices"
+	"sync"
+	"time"
+
+	"hot-coffee/internal/dal"
+	"hot-coffee/models"
+)
+
+// Errors returned by IdempotencyService.Begin
+var (
+	ErrIdempotencyKeyReused     = errors.New("Idempotency key was already used for a different request")
+	ErrIdempotencyKeyInProgress = errors.New("A request with this idempotency key is still in progress")
+)
+
+// IdempotencyService remembers the responses to requests made with an Idempotency-Key for
+// a limited time, so a retried request gets the original response instead of running twice
+type IdempotencyService interface {
+	// Begin returns the stored response for the key, or nil after reserving the key for a
+	// request that is about to run and must then be completed or abandoned.
+	Begin(key, request, requestHash string) (*models.IdempotentResponse, error)
+	Complete(response models.IdempotentResponse) error
+	Abandon(key string)
+	// Reload runs replace, which rewrites the stored responses on disk, while no response
+	// can be stored, and then serves the responses it wrote.
+	Reload(replace func() error) error
+}
+
+type idempotencyService struct {
+	repo      dal.IdempotencyRepository
+	ttl       time.Duration
+	mu        sync.Mutex
+	responses map[string]models.IdempotentResponse
+	running   map[string]bool // Keys of requests that have begun but not completed.
+}
+
+// Creates an idempotency service keeping responses for ttl, loading the unexpired stored ones
+func NewIdempotencyService(repo dal.IdempotencyRepository, ttl time.Duration) (IdempotencyService, error) {
+	stored, err := repo.ReadResponses()
+	if err != nil {
+		return nil, err
+	}
+	s := &idempotencyService{
+		repo:      repo,
+		ttl:       ttl,
+		responses: make(map[string]models.IdempotentResponse, len(stored)),
+		running:   make(map[string]bool),
+	}
+	for _, response := range stored {
+		s.responses[response.Key] = response
+	}
+	s.pruneExpired()
+	return s, nil
+}
+
+// Returns the stored response for a repeated request, or nil and reserves the key for a new one
+func (s *idempotencyService) Begin(key, request, requestHash string) (*models.IdempotentResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.running[key] {
+		return nil, ErrIdempotencyKeyInProgress
+	}
+	if response, exists := s.responses[key]; exists && !s.expired(response) {
+		if response.Request != request || response.RequestHash != requestHash {
+			return nil, ErrIdempotencyKeyReused
+		}
+		return &response, nil
+	}
+	s.running[key] = true
+	return nil, nil
+}
+
+// Stores the response to a request begun with Begin and persists all unexpired responses
+func (s *idempotencyService) Complete(response models.IdempotentResponse) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.running, response.Key)
+	s.responses[response.Key] = response
+	s.pruneExpired()
+	stored := make([]models.IdempotentResponse, 0, len(s.responses))
+	for _, response := range s.responses {
+		stored = append(stored, response)
+	}
+	slices.SortFunc(stored, func(a, b models.IdempotentResponse) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+	return s.repo.WriteResponses(stored)
+}
+
+// Releases a key reserved by Begin without storing a response, so the request can be retried
+func (s *idempotencyService) Abandon(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.running, key)
+}
+
+// Replaces the stored responses through replace and loads the result; keys still running stay reserved
+func (s *idempotencyService) Reload(replace func() error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := replace(); err != nil {
+		return err
+	}
+	stored, err := s.repo.ReadResponses()
+	if err != nil {
+		return err
+	}
+	s.responses = make(map[string]models.IdempotentResponse, len(stored))
+	for _, response := range stored {
+		s.responses[response.Key] = response
+	}
+	s.pruneExpired()
+	return nil
+}
+
+// Reports whether a stored response is older than the TTL
+func (s *idempotencyService) expired(response models.IdempotentResponse) bool {
+	return time.Since(response.CreatedAt) > s.ttl
+}
+
+// Drops expired responses; the caller holds s.mu or owns s exclusively
+func (s *idempotencyService) pruneExpired() {
+	for key, response := range s.responses {
+		if s.expired(response) {
+			delete(s.responses, key)
+		}
+	}
+}
@@ -30,6 +30,8 @@ func NewInvService(invRepo dal.InventoryRepository) InventoryService {
 
 // ServicePostInv adds new inventory items to the inventory if they pass validation and don't already exist.
 func (s *invService) ServicePostInv(content []models.InventoryItem) error {
+	unlock := s.invRepo.Lock(dal.InventoryitemFile)
+	defer unlock()
 	result := []models.InventoryItem{}
 	invItems, err := s.invRepo.ReadJSONInv() // Reads existing inventory items.
 	result = invItems
@@ -44,6 +46,7 @@ func (s *invService) ServicePostInv(content []models.InventoryItem) error {
 		if !CheckIsNew(oneInvItem, invItems) {
 			return errors.New("Such ID already exists") // Ensure each new item has a unique ID.
 		}
+		oneInvItem.Reserved = 0             // Only orders reserve stock.
 		result = append(result, oneInvItem) // Append new valid items to the result list.
 	}
 
@@ -56,25 +59,18 @@ func (s *invService) ServicePostInv(content []models.InventoryItem) error {
 
 // ServiceGetInvItem retrieves all inventory items from storage.
 func (s *invService) ServiceGetInvItem() ([]models.InventoryItem, error) {
+	unlock := s.invRepo.RLock(dal.InventoryitemFile)
+	defer unlock()
 	return s.invRepo.ReadJSONInv()
 }
 
 // ServiceGetInvID retrieves a specific inventory item by its ID.
 // Returns an error if the ID is not found.
 func (s *invService) ServiceGetInvID(id string) (models.InventoryItem, error) {
-	checker := false
-	newGetInvID := models.InventoryItem{}
-	jsonfileinv, err := s.invRepo.ReadJSONInv()
-	if err != nil {
-		return newGetInvID, err
-	}
-	for _, value := range jsonfileinv {
-		if value.IngredientID == id {
-			checker = true
-			newGetInvID = value
-		}
-	}
-	if !checker {
+	unlock := s.invRepo.RLock(dal.InventoryitemFile)
+	defer unlock()
+	newGetInvID, exists := s.invRepo.FindInvItem(id)
+	if !exists {
 		return newGetInvID, errors.New("ID not found")
 	}
 	return newGetInvID, nil
@@ -82,6 +78,8 @@ func (s *invService) ServiceGetInvID(id string) (models.InventoryItem, error) {
 
 // ServicePutInvID updates an existing inventory item identified by ID with new data.
 func (s *invService) ServicePutInvID(id string, newEdit models.InventoryItem) error {
+	unlock := s.invRepo.Lock(dal.InventoryitemFile)
+	defer unlock()
 	checker := false
 	jsonfileinv, err := s.invRepo.ReadJSONInv()
 	if err != nil {
@@ -127,6 +125,9 @@ func (s *invService) EditInvStructure(EditableStructure models.InventoryItem, ne
 	if check, err := s.CheckInvPost(newEditedStructure); !check && err != nil {
 		return newEditedStructure, err // Return error if the edited structure is invalid.
 	}
+	if newEditedStructure.Quantity < newEditedStructure.Reserved {
+		return newEditedStructure, errors.New("Quantity cannot be less than the amount reserved by open orders")
+	}
 
 	return newEditedStructure, nil
 }
@@ -151,6 +152,8 @@ func CheckPutInv(newinv models.InventoryItem) []string {
 // ServiceInvDelete deletes an inventory item by ID.
 // Returns an error if the item with the specified ID is not found.
 func (s *invService) ServiceInvDelete(id string) error {
+	unlock := s.invRepo.Lock(dal.InventoryitemFile)
+	defer unlock()
 	check := false
 	newInv, err := s.invRepo.ReadJSONInv()
 	if err != nil {
@@ -163,10 +166,13 @@ func (s *invService) ServiceInvDelete(id string) error {
 			index = i
 		}
 	}
-	newInv = append(newInv[:index], newInv[index+1:]...) // Remove item by index.
 	if !check {
 		return errors.New("Item not found with this ID")
 	}
+	if newInv[index].Reserved > 0 {
+		return errors.New("Item is reserved by open orders")
+	}
+	newInv = append(newInv[:index], newInv[index+1:]...) // Remove item by index.
 	err = s.invRepo.WriteJSONInv(newInv)
 	if err != nil {
 		return err
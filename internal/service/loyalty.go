@@ -0,0 +1,148 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"time"
+
+	"hot-coffee/models"
+)
+
+// Checks the reward lines of an order: the order must belong to a registered customer and
+// each reward must come from an existing rule that offers the product as a reward
+func checkRewards(order models.Order, findRule func(id string) (models.LoyaltyRule, bool)) error {
+	for _, item := range order.Items {
+		if item.Reward == "" {
+			continue
+		}
+		if order.CustomerID == "" {
+			return errors.New("Rewards can only be redeemed on orders of registered customers")
+		}
+		rule, exists := findRule(item.Reward)
+		if !exists {
+			return fmt.Errorf("Unknown loyalty rule: %s", item.Reward)
+		}
+		if !slices.Contains(rule.RewardProductIDs, item.ProductID) {
+			return fmt.Errorf("%s is not a reward of loyalty rule %s", item.ProductID, rule.ID)
+		}
+	}
+	return nil
+}
+
+// Returns a customer's balance under a rule
+func ledgerBalance(ledger []models.LoyaltyEntry, customerID, ruleID string) int {
+	balance := 0
+	for _, entry := range ledger {
+		if entry.CustomerID == customerID && entry.RuleID == ruleID {
+			balance += entry.Points
+		}
+	}
+	return balance
+}
+
+// Debits the rewards taken in an order from the customer's balances, failing if a balance
+// does not cover them
+func redeemRewards(ledger []models.LoyaltyEntry, order models.Order, findRule func(id string) (models.LoyaltyRule, bool), now time.Time) ([]models.LoyaltyEntry, error) {
+	costs := make(map[string]int)
+	var ruleIDs []string
+	for _, item := range order.Items {
+		if item.Reward == "" {
+			continue
+		}
+		rule, exists := findRule(item.Reward)
+		if !exists {
+			return ledger, fmt.Errorf("Unknown loyalty rule: %s", item.Reward)
+		}
+		if _, seen := costs[rule.ID]; !seen {
+			ruleIDs = append(ruleIDs, rule.ID)
+		}
+		costs[rule.ID] += rule.RewardCost * item.Quantity
+	}
+	for _, ruleID := range ruleIDs {
+		if balance := ledgerBalance(ledger, order.CustomerID, ruleID); balance < costs[ruleID] {
+			return ledger, fmt.Errorf("Not enough loyalty balance for rule %s: %d needed, %d available", ruleID, costs[ruleID], balance)
+		}
+		ledger = appendEntry(ledger, models.LoyaltyEntry{
+			CustomerID: order.CustomerID, RuleID: ruleID, OrderID: order.ID,
+			Kind: models.LedgerRedeem, Points: -costs[ruleID],
+		}, now)
+	}
+	return ledger, nil
+}
+
+// Credits the customer of a closed order with what the order earns under every rule
+func earnRewards(ledger []models.LoyaltyEntry, order models.Order, rules []models.LoyaltyRule, now time.Time) []models.LoyaltyEntry {
+	if order.CustomerID == "" {
+		return ledger
+	}
+	for _, rule := range rules {
+		if points := earnedPoints(order, rule); points > 0 {
+			ledger = appendEntry(ledger, models.LoyaltyEntry{
+				CustomerID: order.CustomerID, RuleID: rule.ID, OrderID: order.ID,
+				Kind: models.LedgerEarn, Points: points,
+			}, now)
+		}
+	}
+	return ledger
+}
+
+// Returns what an order earns under a rule; reward lines earn nothing
+func earnedPoints(order models.Order, rule models.LoyaltyRule) int {
+	stamps, spent := 0, 0.0
+	for _, item := range order.Items {
+		if item.Reward != "" || len(rule.ProductIDs) > 0 && !slices.Contains(rule.ProductIDs, item.ProductID) {
+			continue
+		}
+		stamps += item.Quantity
+		spent += item.LineTotal - item.Discount
+	}
+	if rule.Kind == models.LoyaltyStamps {
+		return stamps
+	}
+	// The small margin keeps amounts like 2.9999999 from losing a point to floating point error
+	return int(math.Floor(spent*rule.PointsPerUnit + 1e-9))
+}
+
+// Undoes everything an order has earned and redeemed so far, so the balances are as if
+// the order had never been placed
+func reverseOrderEntries(ledger []models.LoyaltyEntry, orderID string, reason string, now time.Time) []models.LoyaltyEntry {
+	type account struct{ customerID, ruleID string }
+	net := make(map[account]int)
+	var accounts []account
+	for _, entry := range ledger {
+		if entry.OrderID != orderID {
+			continue
+		}
+		key := account{entry.CustomerID, entry.RuleID}
+		if _, seen := net[key]; !seen {
+			accounts = append(accounts, key)
+		}
+		net[key] += entry.Points
+	}
+	for _, key := range accounts {
+		if net[key] == 0 {
+			continue
+		}
+		ledger = appendEntry(ledger, models.LoyaltyEntry{
+			CustomerID: key.customerID, RuleID: key.ruleID, OrderID: orderID,
+			Kind: models.LedgerReverse, Points: -net[key], Reason: reason,
+		}, now)
+	}
+	return ledger
+}
+
+// Appends an entry to the ledger under the next free entry ID
+func appendEntry(ledger []models.LoyaltyEntry, entry models.LoyaltyEntry, now time.Time) []models.LoyaltyEntry {
+	last := 0
+	for _, existing := range ledger {
+		if n, err := strconv.Atoi(existing.ID); err == nil && n > last {
+			last = n
+		}
+	}
+	entry.ID = strconv.Itoa(last + 1)
+	entry.At = now.Format(models.TimeLayout)
+	return append(ledger, entry)
+}
@@ -0,0 +1,212 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"hot-coffee/internal/dal"
+	"hot-coffee/models"
+)
+
+// ErrLoyaltyRuleNotFound is returned for a rule ID that does not name an existing loyalty rule
+var ErrLoyaltyRuleNotFound = errors.New("Loyalty rule not found")
+
+// LoyaltyService manages the loyalty rules and reports and adjusts customers' balances
+type LoyaltyService interface {
+	ListRules() ([]models.LoyaltyRule, error)
+	CreateRule(rule models.LoyaltyRule) error
+	UpdateRule(id string, rule models.LoyaltyRule) error
+	DeleteRule(id string) error
+	Balances(customerID string) ([]models.LoyaltyBalance, error)
+	Ledger(customerID string) ([]models.LoyaltyEntry, error)
+	Adjust(customerID string, adjustment models.LoyaltyAdjustment) (models.LoyaltyEntry, error)
+}
+
+type loyaltyService struct {
+	loyaltyRepo dal.LoyaltyRepository
+}
+
+// Initializes and returns a new instance of loyaltyService with the provided repository
+func NewLoyaltyService(loyaltyRepo dal.LoyaltyRepository) LoyaltyService {
+	return &loyaltyService{loyaltyRepo: loyaltyRepo}
+}
+
+// Returns all loyalty rules
+func (s *loyaltyService) ListRules() ([]models.LoyaltyRule, error) {
+	unlock := s.loyaltyRepo.RLock(dal.LoyaltyRulesFile)
+	defer unlock()
+	return s.loyaltyRepo.ReadRules()
+}
+
+// Adds a loyalty rule under a new ID
+func (s *loyaltyService) CreateRule(rule models.LoyaltyRule) error {
+	unlock := s.loyaltyRepo.Lock(dal.LoyaltyRulesFile, dal.MenuItemFile)
+	defer unlock()
+	if err := s.checkRule(rule); err != nil {
+		return err
+	}
+	rules, err := s.loyaltyRepo.ReadRules()
+	if err != nil {
+		return err
+	}
+	if _, exists := s.loyaltyRepo.FindRule(rule.ID); exists {
+		return errors.New("Such ID already exists")
+	}
+	return s.loyaltyRepo.WriteRules(append(rules, rule))
+}
+
+// Replaces a loyalty rule; balances collected under it are kept and follow the new terms
+func (s *loyaltyService) UpdateRule(id string, rule models.LoyaltyRule) error {
+	unlock := s.loyaltyRepo.Lock(dal.LoyaltyRulesFile, dal.MenuItemFile)
+	defer unlock()
+	rule.ID = id
+	if err := s.checkRule(rule); err != nil {
+		return err
+	}
+	rules, err := s.loyaltyRepo.ReadRules()
+	if err != nil {
+		return err
+	}
+	i := slices.IndexFunc(rules, func(r models.LoyaltyRule) bool { return r.ID == id })
+	if i < 0 {
+		return ErrLoyaltyRuleNotFound
+	}
+	rules[i] = rule
+	return s.loyaltyRepo.WriteRules(rules)
+}
+
+// Deletes a loyalty rule; its ledger entries are kept, so re-creating the rule restores the balances
+func (s *loyaltyService) DeleteRule(id string) error {
+	unlock := s.loyaltyRepo.Lock(dal.LoyaltyRulesFile)
+	defer unlock()
+	rules, err := s.loyaltyRepo.ReadRules()
+	if err != nil {
+		return err
+	}
+	i := slices.IndexFunc(rules, func(r models.LoyaltyRule) bool { return r.ID == id })
+	if i < 0 {
+		return ErrLoyaltyRuleNotFound
+	}
+	return s.loyaltyRepo.WriteRules(slices.Delete(rules, i, i+1))
+}
+
+// Returns a customer's balance under every rule
+func (s *loyaltyService) Balances(customerID string) ([]models.LoyaltyBalance, error) {
+	unlock := s.loyaltyRepo.RLock(dal.LoyaltyRulesFile, dal.LoyaltyLedgerFile, dal.CustomersFile)
+	defer unlock()
+	if _, exists := s.loyaltyRepo.FindCustomer(customerID); !exists {
+		return nil, ErrCustomerNotFound
+	}
+	rules, err := s.loyaltyRepo.ReadRules()
+	if err != nil {
+		return nil, err
+	}
+	ledger, err := s.loyaltyRepo.ReadLedger()
+	if err != nil {
+		return nil, err
+	}
+	balances := make([]models.LoyaltyBalance, 0, len(rules))
+	for _, rule := range rules {
+		balance := ledgerBalance(ledger, customerID, rule.ID)
+		balances = append(balances, models.LoyaltyBalance{
+			RuleID:     rule.ID,
+			Name:       rule.Name,
+			Kind:       rule.Kind,
+			Balance:    balance,
+			RewardCost: rule.RewardCost,
+			Rewards:    max(balance, 0) / rule.RewardCost,
+		})
+	}
+	return balances, nil
+}
+
+// Returns a customer's ledger entries, oldest first
+func (s *loyaltyService) Ledger(customerID string) ([]models.LoyaltyEntry, error) {
+	unlock := s.loyaltyRepo.RLock(dal.LoyaltyLedgerFile, dal.CustomersFile)
+	defer unlock()
+	if _, exists := s.loyaltyRepo.FindCustomer(customerID); !exists {
+		return nil, ErrCustomerNotFound
+	}
+	ledger, err := s.loyaltyRepo.ReadLedger()
+	if err != nil {
+		return nil, err
+	}
+	entries := []models.LoyaltyEntry{}
+	for _, entry := range ledger {
+		if entry.CustomerID == customerID {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+// Credits or debits a customer's balance by hand; a debit cannot take the balance below zero
+func (s *loyaltyService) Adjust(customerID string, adjustment models.LoyaltyAdjustment) (models.LoyaltyEntry, error) {
+	unlock := s.loyaltyRepo.Lock(dal.LoyaltyRulesFile, dal.LoyaltyLedgerFile, dal.CustomersFile)
+	defer unlock()
+	if _, exists := s.loyaltyRepo.FindCustomer(customerID); !exists {
+		return models.LoyaltyEntry{}, ErrCustomerNotFound
+	}
+	if _, exists := s.loyaltyRepo.FindRule(adjustment.RuleID); !exists {
+		return models.LoyaltyEntry{}, ErrLoyaltyRuleNotFound
+	}
+	reason := strings.TrimSpace(adjustment.Reason)
+	if reason == "" {
+		return models.LoyaltyEntry{}, errors.New("Missing reason")
+	}
+	if adjustment.Points == 0 {
+		return models.LoyaltyEntry{}, errors.New("Points cannot be zero")
+	}
+	ledger, err := s.loyaltyRepo.ReadLedger()
+	if err != nil {
+		return models.LoyaltyEntry{}, err
+	}
+	if balance := ledgerBalance(ledger, customerID, adjustment.RuleID); balance+adjustment.Points < 0 {
+		return models.LoyaltyEntry{}, fmt.Errorf("The balance is only %d", balance)
+	}
+	ledger = appendEntry(ledger, models.LoyaltyEntry{
+		CustomerID: customerID, RuleID: adjustment.RuleID,
+		Kind: models.LedgerAdjust, Points: adjustment.Points, Reason: reason,
+	}, time.Now())
+	if err := s.loyaltyRepo.WriteLedger(ledger); err != nil {
+		return models.LoyaltyEntry{}, err
+	}
+	return ledger[len(ledger)-1], nil
+}
+
+// Validates a loyalty rule; every product it names must be on the menu
+func (s *loyaltyService) checkRule(rule models.LoyaltyRule) error {
+	if strings.TrimSpace(rule.ID) == "" {
+		return errors.New("Missing rule id")
+	}
+	if strings.TrimSpace(rule.Name) == "" {
+		return errors.New("Missing rule name")
+	}
+	switch rule.Kind {
+	case models.LoyaltyStamps:
+		if rule.PointsPerUnit != 0 {
+			return errors.New("Stamp rules earn one stamp per item and take no points per unit")
+		}
+	case models.LoyaltyPoints:
+		if rule.PointsPerUnit <= 0 {
+			return errors.New("Points per unit must be positive")
+		}
+	default:
+		return fmt.Errorf("Unknown rule kind: %s", rule.Kind)
+	}
+	if rule.RewardCost < 1 {
+		return errors.New("Reward cost must be at least 1")
+	}
+	if len(rule.RewardProductIDs) == 0 {
+		return errors.New("Missing reward products")
+	}
+	for _, productID := range slices.Concat(rule.ProductIDs, rule.RewardProductIDs) {
+		if _, exists := s.loyaltyRepo.FindMenuItem(productID); !exists {
+			return fmt.Errorf("This item is not on the menu: %s", productID)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"hot-coffee/models"
+)
+
+// Returns a rule lookup over the given rules
+func findRuleIn(rules ...models.LoyaltyRule) func(id string) (models.LoyaltyRule, bool) {
+	return func(id string) (models.LoyaltyRule, bool) {
+		for _, rule := range rules {
+			if rule.ID == id {
+				return rule, true
+			}
+		}
+		return models.LoyaltyRule{}, false
+	}
+}
+
+func TestEarnedPoints(t *testing.T) {
+	order := models.Order{Items: []models.OrderItem{
+		{ProductID: "latte", Quantity: 2, LineTotal: 7, Discount: 1},
+		{ProductID: "muffin", Quantity: 1, LineTotal: 2.5},
+		{ProductID: "latte", Quantity: 1, LineTotal: 0, Reward: "card"},
+	}}
+	tests := []struct {
+		name string
+		rule models.LoyaltyRule
+		want int
+	}{
+		{name: "stamps on every product", rule: models.LoyaltyRule{Kind: models.LoyaltyStamps}, want: 3},
+		{name: "stamps on listed products", rule: models.LoyaltyRule{Kind: models.LoyaltyStamps, ProductIDs: []string{"latte"}}, want: 2},
+		{name: "points after discounts", rule: models.LoyaltyRule{Kind: models.LoyaltyPoints, PointsPerUnit: 1}, want: 8},
+		{name: "points rounded down", rule: models.LoyaltyRule{Kind: models.LoyaltyPoints, PointsPerUnit: 0.5, ProductIDs: []string{"muffin"}}, want: 1},
+		{name: "points without floating point loss", rule: models.LoyaltyRule{Kind: models.LoyaltyPoints, PointsPerUnit: 10, ProductIDs: []string{"latte"}}, want: 60},
+		{name: "nothing qualifies", rule: models.LoyaltyRule{Kind: models.LoyaltyStamps, ProductIDs: []string{"tea"}}, want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := earnedPoints(order, tt.rule); got != tt.want {
+				t.Errorf("earnedPoints() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRedeemRewards(t *testing.T) {
+	now := time.Date(2026, 10, 17, 9, 0, 0, 0, time.UTC)
+	card := models.LoyaltyRule{ID: "card", Kind: models.LoyaltyStamps, RewardCost: 9, RewardProductIDs: []string{"latte"}}
+	points := models.LoyaltyRule{ID: "points", Kind: models.LoyaltyPoints, RewardCost: 50, RewardProductIDs: []string{"muffin"}}
+	ledger := []models.LoyaltyEntry{
+		{ID: "1", CustomerID: "1", RuleID: "card", Kind: models.LedgerEarn, Points: 12},
+		{ID: "2", CustomerID: "1", RuleID: "points", Kind: models.LedgerEarn, Points: 60},
+		{ID: "3", CustomerID: "2", RuleID: "card", Kind: models.LedgerEarn, Points: 30},
+	}
+	reward := func(ruleID, productID string, quantity int) models.OrderItem {
+		return models.OrderItem{ProductID: productID, Quantity: quantity, Reward: ruleID}
+	}
+	tests := []struct {
+		name    string
+		items   []models.OrderItem
+		want    []models.LoyaltyEntry // Entries added to the ledger.
+		wantErr bool
+	}{
+		{name: "no rewards", items: []models.OrderItem{{ProductID: "latte", Quantity: 1}}},
+		{
+			name:  "one debit per rule",
+			items: []models.OrderItem{reward("card", "latte", 1), reward("points", "muffin", 1)},
+			want: []models.LoyaltyEntry{
+				{ID: "4", CustomerID: "1", RuleID: "card", OrderID: "7", Kind: models.LedgerRedeem, Points: -9, At: now.Format(models.TimeLayout)},
+				{ID: "5", CustomerID: "1", RuleID: "points", OrderID: "7", Kind: models.LedgerRedeem, Points: -50, At: now.Format(models.TimeLayout)},
+			},
+		},
+		{name: "balance of another customer does not count", items: []models.OrderItem{reward("card", "latte", 2)}, wantErr: true},
+		{name: "lines of one rule add up", items: []models.OrderItem{reward("card", "latte", 1), reward("card", "latte", 1)}, wantErr: true},
+		{name: "unknown rule", items: []models.OrderItem{reward("stamps", "latte", 1)}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			order := models.Order{ID: "7", CustomerID: "1", Items: tt.items}
+			got, err := redeemRewards(ledger, order, findRuleIn(card, points), now)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("redeemRewards() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			added := got[len(ledger):]
+			if len(added) != len(tt.want) {
+				t.Fatalf("redeemRewards() added %+v, want %+v", added, tt.want)
+			}
+			for i := range added {
+				if added[i] != tt.want[i] {
+					t.Errorf("entry %d is %+v, want %+v", i, added[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+// Reversing leaves every balance the order touched as if the order had never been placed,
+// and adds nothing for accounts the order already nets to zero on
+func TestReverseOrderEntries(t *testing.T) {
+	now := time.Date(2026, 10, 17, 9, 0, 0, 0, time.UTC)
+	ledger := []models.LoyaltyEntry{
+		{ID: "1", CustomerID: "1", RuleID: "card", OrderID: "5", Kind: models.LedgerEarn, Points: 4},
+		{ID: "2", CustomerID: "1", RuleID: "card", OrderID: "7", Kind: models.LedgerRedeem, Points: -9},
+		{ID: "3", CustomerID: "1", RuleID: "points", OrderID: "7", Kind: models.LedgerRedeem, Points: -50},
+		{ID: "4", CustomerID: "1", RuleID: "card", OrderID: "7", Kind: models.LedgerEarn, Points: 2},
+		{ID: "5", CustomerID: "1", RuleID: "bonus", OrderID: "7", Kind: models.LedgerEarn, Points: 3},
+		{ID: "6", CustomerID: "1", RuleID: "bonus", OrderID: "7", Kind: models.LedgerReverse, Points: -3},
+	}
+	got := reverseOrderEntries(ledger, "7", "Order cancelled", now)
+	want := []models.LoyaltyEntry{
+		{ID: "7", CustomerID: "1", RuleID: "card", OrderID: "7", Kind: models.LedgerReverse, Points: 7, Reason: "Order cancelled", At: now.Format(models.TimeLayout)},
+		{ID: "8", CustomerID: "1", RuleID: "points", OrderID: "7", Kind: models.LedgerReverse, Points: 50, Reason: "Order cancelled", At: now.Format(models.TimeLayout)},
+	}
+	added := got[len(ledger):]
+	if len(added) != len(want) {
+		t.Fatalf("reverseOrderEntries() added %+v, want %+v", added, want)
+	}
+	for i := range added {
+		if added[i] != want[i] {
+			t.Errorf("entry %d is %+v, want %+v", i, added[i], want[i])
+		}
+	}
+	for _, ruleID := range []string{"card", "points", "bonus"} {
+		if balance, before := ledgerBalance(got, "1", ruleID), ledgerBalance(ledger[:1], "1", ruleID); balance != before {
+			t.Errorf("balance under %s after reversal is %d, want %d", ruleID, balance, before)
+		}
+	}
+	if again := reverseOrderEntries(got, "7", "Order cancelled", now); len(again) != len(got) {
+		t.Errorf("reversing twice added %+v", again[len(got):])
+	}
+}
@@ -30,6 +30,8 @@ func NewMenuService(menuRepo dal.MenuRepository) MenuService {
 
 // Adds new menu items to the menu, checking for duplicates and validating data
 func (s *menuService) ServicePostMenu(content []models.MenuItem) error {
+	unlock := s.menuRepo.Lock(dal.InventoryitemFile, dal.MenuItemFile)
+	defer unlock()
 	result := []models.MenuItem{}
 	menuItems, err := s.menuRepo.ReadJSONMenu()
 	result = menuItems
@@ -62,25 +64,17 @@ func (s *menuService) ServicePostMenu(content []models.MenuItem) error {
 
 // Retrieves all menu items from the repository
 func (s *menuService) ServiceGetMenuItem() ([]models.MenuItem, error) {
+	unlock := s.menuRepo.RLock(dal.MenuItemFile)
+	defer unlock()
 	return s.menuRepo.ReadJSONMenu()
 }
 
 // Retrieves a specific menu item by ID, returning an error if not found
 func (s *menuService) ServiceGetMenuID(id string) (models.MenuItem, error) {
-	checker := false
-	newGetMenuID := models.MenuItem{}
-	jsonfilemenu, err := s.menuRepo.ReadJSONMenu()
-	if err != nil {
-		return newGetMenuID, err
-	}
-	for _, value := range jsonfilemenu {
-		if value.ID == id {
-			checker = true
-			newGetMenuID = value
-
-		}
-	}
-	if !checker {
+	unlock := s.menuRepo.RLock(dal.MenuItemFile)
+	defer unlock()
+	newGetMenuID, exists := s.menuRepo.FindMenuItem(id)
+	if !exists {
 		return newGetMenuID, errors.New("ID not found")
 	}
 	return newGetMenuID, nil
@@ -88,6 +82,8 @@ func (s *menuService) ServiceGetMenuID(id string) (models.MenuItem, error) {
 
 // Updates a specific menu item by ID with new data provided, validating changes
 func (s *menuService) ServicePutMenuID(id string, newEdit models.MenuItem) error {
+	unlock := s.menuRepo.Lock(dal.InventoryitemFile, dal.MenuItemFile)
+	defer unlock()
 	checker := false
 	jsonfilemenu, err := s.menuRepo.ReadJSONMenu()
 	if err != nil {
@@ -128,9 +124,15 @@ func (s *menuService) EditStructureMenu(EditableStructure models.MenuItem, newEd
 			newEditedStructure.Name = newEdit.Name
 		case "description":
 			newEditedStructure.Description = newEdit.Description
+		case "category":
+			newEditedStructure.Category = newEdit.Category
 
 		case "ingredients":
 			newEditedStructure.Ingredients = newEdit.Ingredients
+		case "variants":
+			newEditedStructure.Variants = newEdit.Variants
+		case "modifier_groups":
+			newEditedStructure.ModifierGroups = newEdit.ModifierGroups
 		}
 	}
 	if check, err := s.CheckMenu(newEditedStructure); !check {
@@ -150,6 +152,9 @@ func (s *menuService) CheckPutMenu(newmenu models.MenuItem) ([]string, error) {
 	if newmenuDescription != "" {
 		listMenu = append(listMenu, "description")
 	}
+	if strings.TrimSpace(newmenu.Category) != "" {
+		listMenu = append(listMenu, "category")
+	}
 
 	for _, msq := range newmenu.Ingredients {
 		if err := s.checkIngredients(msq.IngredientID); err != nil {
@@ -160,11 +165,20 @@ func (s *menuService) CheckPutMenu(newmenu models.MenuItem) ([]string, error) {
 			listMenu = append(listMenu, "ingredients")
 		}
 	}
+	// An empty list removes all variants or modifier groups; leaving the field out keeps them
+	if newmenu.Variants != nil {
+		listMenu = append(listMenu, "variants")
+	}
+	if newmenu.ModifierGroups != nil {
+		listMenu = append(listMenu, "modifier_groups")
+	}
 	return listMenu, nil
 }
 
 // Deletes a menu item by ID, returning an error if the ID is not found
 func (s *menuService) ServiceDelete(id string) error {
+	unlock := s.menuRepo.Lock(dal.InventoryitemFile, dal.MenuItemFile)
+	defer unlock()
 	check := false
 	newMenu, err := s.menuRepo.ReadJSONMenu()
 	if err != nil {
@@ -224,26 +238,20 @@ func (s *menuService) CheckMenu(newmenu models.MenuItem) (bool, error) {
 			return false, errors.New("Missing ingredients ID")
 		}
 	}
+	if err := checkVariants(newmenu, s.checkIngredients); err != nil {
+		return false, err
+	}
+	if err := checkModifierGroups(newmenu, s.checkIngredients); err != nil {
+		return false, err
+	}
 
 	return true, nil
 }
 
 // Checks if the given ingredient ID exists in the inventory, returning an error if it is missing
 func (s *menuService) checkIngredients(IngredientId string) error {
-	fileInv, err := s.menuRepo.ReadJSONInventory()
-	if err != nil {
-		return err
-	}
-	IngredientMap := make(map[string]int)
-
-	missList := ""
-	for _, oneInvItem := range fileInv {
-		IngredientMap[oneInvItem.IngredientID] = int(oneInvItem.Quantity)
-	}
-	_, exists := IngredientMap[IngredientId]
-	if !exists {
-		missList = IngredientId
-		return errors.New(fmt.Sprintf("This ingredient is not in the inventory: %s", missList))
+	if _, exists := s.menuRepo.FindInvItem(IngredientId); !exists {
+		return errors.New(fmt.Sprintf("This ingredient is not in the inventory: %s", IngredientId))
 	}
 	return nil
 }
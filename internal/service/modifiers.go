@@ -0,0 +1,162 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"hot-coffee/models"
+)
+
+// Returns the modifier options chosen for an order item, checking them against the
+// modifier groups of its menu item
+func resolveModifiers(menuItem models.MenuItem, chosen []string) ([]models.Modifier, error) {
+	type option struct {
+		group    int
+		modifier models.Modifier
+	}
+	options := make(map[string]option)
+	for g, group := range menuItem.ModifierGroups {
+		for _, modifier := range group.Options {
+			options[modifier.ID] = option{group: g, modifier: modifier}
+		}
+	}
+
+	result := []models.Modifier{}
+	counts := make([]int, len(menuItem.ModifierGroups))
+	seen := make(map[string]bool, len(chosen))
+	for _, id := range chosen {
+		opt, exists := options[id]
+		if !exists {
+			return nil, fmt.Errorf("Unknown modifier %s for %s", id, menuItem.ID)
+		}
+		if seen[id] {
+			return nil, fmt.Errorf("Modifier %s chosen more than once for %s", id, menuItem.ID)
+		}
+		seen[id] = true
+		counts[opt.group]++
+		result = append(result, opt.modifier)
+	}
+	for g, group := range menuItem.ModifierGroups {
+		if limit := max(group.MaxChoices, 1); counts[g] > limit {
+			return nil, fmt.Errorf("At most %d options of %s can be chosen for %s", limit, group.Name, menuItem.ID)
+		}
+		if group.Required && counts[g] == 0 {
+			return nil, fmt.Errorf("Missing choice of %s for %s", group.Name, menuItem.ID)
+		}
+	}
+	return result, nil
+}
+
+// Returns a unit price with the modifiers applied, never below zero
+func modifiedPrice(price float64, modifiers []models.Modifier) float64 {
+	for _, modifier := range modifiers {
+		price += modifier.PriceDelta
+	}
+	return roundMoney(max(price, 0))
+}
+
+// Applies the modifiers to the ingredients of one unit of a menu item, given by ingredient ID
+func modifiedRecipe(recipe map[string]float64, modifiers []models.Modifier) map[string]float64 {
+	for _, modifier := range modifiers {
+		for _, change := range modifier.Changes {
+			switch change.Action {
+			case models.ModifierAdd:
+				recipe[change.IngredientID] += change.Quantity
+			case models.ModifierRemove:
+				delete(recipe, change.IngredientID)
+			case models.ModifierReplace:
+				amount, exists := recipe[change.Replaces]
+				if !exists {
+					continue
+				}
+				delete(recipe, change.Replaces)
+				if change.Quantity > 0 {
+					amount = change.Quantity
+				}
+				recipe[change.IngredientID] += amount
+			}
+		}
+	}
+	return recipe
+}
+
+// Validates the modifier groups of a menu item; checkIngredient reports ingredients missing from the inventory
+func checkModifierGroups(item models.MenuItem, checkIngredient func(id string) error) error {
+	inRecipe := make(map[string]bool, len(item.Ingredients))
+	for _, ingredient := range item.Ingredients {
+		inRecipe[ingredient.IngredientID] = true
+	}
+	for _, variant := range item.Variants {
+		for _, ingredient := range variant.Ingredients {
+			inRecipe[ingredient.IngredientID] = true
+		}
+	}
+	groupIDs := make(map[string]bool)
+	optionIDs := make(map[string]bool)
+	for _, group := range item.ModifierGroups {
+		if strings.TrimSpace(group.ID) == "" {
+			return errors.New("Missing modifier group ID")
+		}
+		if groupIDs[group.ID] {
+			return fmt.Errorf("Duplicate modifier group ID: %s", group.ID)
+		}
+		groupIDs[group.ID] = true
+		if strings.TrimSpace(group.Name) == "" {
+			return fmt.Errorf("Missing name of modifier group %s", group.ID)
+		}
+		if group.MaxChoices < 0 {
+			return fmt.Errorf("Max choices of modifier group %s cannot be negative", group.ID)
+		}
+		if len(group.Options) == 0 {
+			return fmt.Errorf("Modifier group %s has no options", group.ID)
+		}
+		for _, option := range group.Options {
+			if strings.TrimSpace(option.ID) == "" {
+				return fmt.Errorf("Missing modifier ID in group %s", group.ID)
+			}
+			if optionIDs[option.ID] {
+				return fmt.Errorf("Duplicate modifier ID: %s", option.ID)
+			}
+			optionIDs[option.ID] = true
+			if strings.TrimSpace(option.Name) == "" {
+				return fmt.Errorf("Missing name of modifier %s", option.ID)
+			}
+			for _, change := range option.Changes {
+				if err := checkRecipeChange(change, inRecipe, checkIngredient); err != nil {
+					return fmt.Errorf("Modifier %s: %w", option.ID, err)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// Validates one recipe change of a modifier against the recipe of its menu item
+func checkRecipeChange(change models.RecipeChange, inRecipe map[string]bool, checkIngredient func(id string) error) error {
+	if strings.TrimSpace(change.IngredientID) == "" {
+		return errors.New("Missing ingredient ID")
+	}
+	if change.Quantity < 0 {
+		return errors.New("Ingredient quantity cannot be negative")
+	}
+	switch change.Action {
+	case models.ModifierAdd:
+		if change.Quantity == 0 {
+			return errors.New("Added ingredient quantity must be positive")
+		}
+		return checkIngredient(change.IngredientID)
+	case models.ModifierReplace:
+		if !inRecipe[change.Replaces] {
+			return fmt.Errorf("Replaced ingredient is not in the recipe: %s", change.Replaces)
+		}
+		return checkIngredient(change.IngredientID)
+	case models.ModifierRemove:
+		if !inRecipe[change.IngredientID] {
+			return fmt.Errorf("Removed ingredient is not in the recipe: %s", change.IngredientID)
+		}
+		return nil
+	default:
+		return fmt.Errorf("Unknown action %q, expected add, replace or remove", change.Action)
+	}
+}
@@ -0,0 +1,131 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"hot-coffee/internal/dal"
+	"hot-coffee/models"
+)
+
+// ErrUnpaidBalance is returned when an order that is not fully paid is closed
+var ErrUnpaidBalance = errors.New("Order has an unpaid balance")
+
+// Records tenders against an active order, all of them or none. Cash beyond the balance
+// is handed back as change; card and gift card payments cannot exceed the balance.
+func (s *orderService) PayOrder(id string, tenders []models.Payment) (models.OrderPayments, error) {
+	if len(tenders) == 0 {
+		return models.OrderPayments{}, errors.New("Missing tenders")
+	}
+	var paid models.Order
+	err := s.changeOrder(id, models.EventOrderPaid, func(order *models.Order, inventory []models.InventoryItem, ledger *[]models.LoyaltyEntry) error {
+		if !isActiveStatus(order.Status) {
+			return fmt.Errorf("Payments can only be recorded on active orders, the order is %s", order.Status)
+		}
+		now := time.Now().Format(models.TimeLayout)
+		for _, tender := range tenders {
+			if err := addPayment(order, tender, now); err != nil {
+				return err
+			}
+		}
+		paid = *order
+		return nil
+	})
+	if err != nil {
+		return models.OrderPayments{}, err
+	}
+	return orderPayments(paid), nil
+}
+
+// Returns the payments recorded against an order and the balance left to pay
+func (s *orderService) OrderPayments(id string) (models.OrderPayments, error) {
+	unlock := s.orderRepo.RLock(dal.OrdersFile)
+	defer unlock()
+	order, exists := s.orderRepo.FindOrder(id)
+	if !exists {
+		return models.OrderPayments{}, errors.New("ID not found")
+	}
+	return orderPayments(order), nil
+}
+
+// Validates one tender and records it against the order's balance
+func addPayment(order *models.Order, tender models.Payment, now string) error {
+	tender.Method = strings.TrimSpace(tender.Method)
+	switch tender.Method {
+	case models.TenderCash, models.TenderCard, models.TenderGiftCard:
+	default:
+		return fmt.Errorf("Unknown payment method %q, expected %s, %s or %s", tender.Method, models.TenderCash, models.TenderCard, models.TenderGiftCard)
+	}
+	if tender.Amount < 0 || tender.Tip < 0 {
+		return errors.New("Payment amounts and tips cannot be negative")
+	}
+	if tender.Amount == 0 && tender.Tip == 0 {
+		return errors.New("Missing payment amount")
+	}
+	tender.Amount, tender.Tip, tender.Change, tender.Reverses = roundMoney(tender.Amount), roundMoney(tender.Tip), 0, ""
+	balance := balanceDue(*order)
+	if balance <= 0 {
+		return errors.New("Order is already paid")
+	}
+	if tender.Amount > balance {
+		if tender.Method != models.TenderCash {
+			return fmt.Errorf("Payment of %.2f exceeds the balance of %.2f; only cash can be overpaid", tender.Amount, balance)
+		}
+		tender.Change = roundMoney(tender.Amount - balance)
+		tender.Amount = balance
+	}
+	tender.ID = strconv.Itoa(len(order.Payments) + 1)
+	tender.At = now
+	order.Payments = append(order.Payments, tender)
+	order.Paid = roundMoney(order.Paid + tender.Amount)
+	order.Tips = roundMoney(order.Tips + tender.Tip)
+	return nil
+}
+
+// Records a refund of every payment of an order that is not paid back yet, leaving nothing paid
+func reversePayments(order *models.Order, now string) {
+	reversed := make(map[string]bool)
+	for _, payment := range order.Payments {
+		reversed[payment.Reverses] = true
+	}
+	for _, payment := range order.Payments {
+		if payment.Reverses != "" || reversed[payment.ID] {
+			continue
+		}
+		order.Payments = append(order.Payments, models.Payment{
+			ID:        strconv.Itoa(len(order.Payments) + 1),
+			Method:    payment.Method,
+			Amount:    -payment.Amount,
+			Tip:       -payment.Tip,
+			Reference: payment.Reference,
+			Reverses:  payment.ID,
+			At:        now,
+		})
+		order.Paid = roundMoney(order.Paid - payment.Amount)
+		order.Tips = roundMoney(order.Tips - payment.Tip)
+	}
+}
+
+// Returns the part of an order's total not paid yet
+func balanceDue(order models.Order) float64 {
+	return max(roundMoney(order.Total-order.Paid), 0)
+}
+
+// Summarizes the payments of an order
+func orderPayments(order models.Order) models.OrderPayments {
+	payments := order.Payments
+	if payments == nil {
+		payments = []models.Payment{}
+	}
+	return models.OrderPayments{
+		OrderID:  order.ID,
+		Total:    order.Total,
+		Paid:     order.Paid,
+		Tips:     order.Tips,
+		Balance:  balanceDue(order),
+		Payments: payments,
+	}
+}
@@ -0,0 +1,94 @@
+package service
+
+import (
+	"slices"
+	"testing"
+
+	"hot-coffee/models"
+)
+
+func TestAddPayment(t *testing.T) {
+	const now = "2026-10-17T09:00:00Z"
+	tests := []struct {
+		name    string
+		paid    float64
+		tender  models.Payment
+		want    models.Payment // The payment recorded.
+		wantErr bool
+	}{
+		{
+			name:   "part of the balance",
+			tender: models.Payment{Method: models.TenderCard, Amount: 4, Tip: 0.5, Reference: "tx-1"},
+			want:   models.Payment{ID: "1", Method: models.TenderCard, Amount: 4, Tip: 0.5, Reference: "tx-1", At: now},
+		},
+		{
+			name:   "cash beyond the balance is change",
+			paid:   4,
+			tender: models.Payment{Method: models.TenderCash, Amount: 10},
+			want:   models.Payment{ID: "1", Method: models.TenderCash, Amount: 6, Change: 4, At: now},
+		},
+		{
+			name:   "amounts are rounded to cents",
+			tender: models.Payment{Method: models.TenderGiftCard, Amount: 2.499},
+			want:   models.Payment{ID: "1", Method: models.TenderGiftCard, Amount: 2.5, At: now},
+		},
+		{
+			name:   "client-set change and reversal are ignored",
+			tender: models.Payment{Method: models.TenderCash, Amount: 10, Change: 7, Reverses: "1"},
+			want:   models.Payment{ID: "1", Method: models.TenderCash, Amount: 10, At: now},
+		},
+		{name: "card beyond the balance", tender: models.Payment{Method: models.TenderCard, Amount: 11}, wantErr: true},
+		{name: "nothing owed", paid: 10, tender: models.Payment{Method: models.TenderCash, Amount: 5}, wantErr: true},
+		{name: "tip when nothing is owed", paid: 10, tender: models.Payment{Method: models.TenderCard, Tip: 1}, wantErr: true},
+		{name: "unknown method", tender: models.Payment{Method: "cheque", Amount: 5}, wantErr: true},
+		{name: "negative amount", tender: models.Payment{Method: models.TenderCash, Amount: -5}, wantErr: true},
+		{name: "no amount", tender: models.Payment{Method: models.TenderCash}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			order := models.Order{Total: 10, Paid: tt.paid}
+			err := addPayment(&order, tt.tender, now)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("addPayment() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				if len(order.Payments) != 0 || order.Paid != tt.paid {
+					t.Errorf("a rejected tender changed the order: %+v", order)
+				}
+				return
+			}
+			if len(order.Payments) != 1 || order.Payments[0] != tt.want {
+				t.Fatalf("payments are %+v, want %+v", order.Payments, tt.want)
+			}
+			if order.Paid != roundMoney(tt.paid+tt.want.Amount) || order.Tips != tt.want.Tip {
+				t.Errorf("order paid %.2f with %.2f in tips, want %.2f and %.2f", order.Paid, order.Tips, tt.paid+tt.want.Amount, tt.want.Tip)
+			}
+		})
+	}
+}
+
+// A refund pays back every payment once, by the same method, and leaves nothing paid
+func TestReversePayments(t *testing.T) {
+	const paidAt, refundedAt = "2026-10-17T09:00:00Z", "2026-10-17T10:00:00Z"
+	order := models.Order{Total: 10, Paid: 10, Tips: 1.5, Payments: []models.Payment{
+		{ID: "1", Method: models.TenderCard, Amount: 6, Tip: 1.5, Reference: "tx-1", At: paidAt},
+		{ID: "2", Method: models.TenderCash, Amount: 4, Change: 1, At: paidAt},
+	}}
+	reversePayments(&order, refundedAt)
+	want := []models.Payment{
+		order.Payments[0],
+		order.Payments[1],
+		{ID: "3", Method: models.TenderCard, Amount: -6, Tip: -1.5, Reference: "tx-1", Reverses: "1", At: refundedAt},
+		{ID: "4", Method: models.TenderCash, Amount: -4, Reverses: "2", At: refundedAt},
+	}
+	if !slices.Equal(order.Payments, want) {
+		t.Errorf("payments are %+v, want %+v", order.Payments, want)
+	}
+	if order.Paid != 0 || order.Tips != 0 {
+		t.Errorf("order paid %.2f with %.2f in tips after the refund, want nothing", order.Paid, order.Tips)
+	}
+	reversePayments(&order, refundedAt)
+	if len(order.Payments) != len(want) {
+		t.Errorf("refunding again added %+v", order.Payments[len(want):])
+	}
+}
@@ -0,0 +1,82 @@
+package service
+
+import (
+	"errors"
+	"log/slog"
+	"time"
+
+	"hot-coffee/internal/dal"
+	"hot-coffee/models"
+)
+
+// How often the pickup scheduler looks for scheduled orders that are due
+const PickupCheckInterval = 15 * time.Second
+
+// Parses the requested pickup time of an order, which must lie in the future
+func parsePickupTime(value string, now time.Time) (time.Time, error) {
+	pickup, err := time.ParseInLocation(models.TimeLayout, value, time.Local)
+	if err != nil {
+		return pickup, errors.New("Invalid pickup time, expected YYYY-MM-DD HH:MM:SS")
+	}
+	if !pickup.After(now) {
+		return pickup, errors.New("Pickup time must be in the future")
+	}
+	return pickup, nil
+}
+
+// Reports whether a scheduled order with this pickup time belongs in the active queue at now
+func (s *orderService) pickupDue(pickupAt string, now time.Time) bool {
+	pickup, err := time.ParseInLocation(models.TimeLayout, pickupAt, time.Local)
+	return err != nil || !now.Before(pickup.Add(-s.pickupLead))
+}
+
+// Moves the scheduled orders whose pickup time is within the lead time into the active queue.
+// Their ingredients were reserved when they were placed, but only against the orders due
+// before them, so an order whose stock was used up meanwhile is activated with a warning.
+func (s *orderService) ActivateScheduledOrders() error {
+	unlock := s.orderRepo.Lock(dal.InventoryitemFile, dal.OrdersFile)
+	defer unlock()
+	orders, err := s.orderRepo.ReadJSONOrder()
+	if err != nil {
+		return err
+	}
+	inventory, err := s.orderRepo.ReadJSONInv()
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	var activated []int
+	for i, order := range orders {
+		if order.Status == models.StatusScheduled && s.pickupDue(order.PickupAt, now) {
+			setStatus(&orders[i], models.StatusOpen, now)
+			activated = append(activated, i)
+			slog.Info("Scheduled order activated", slog.String("order", order.ID), slog.String("pickup_at", order.PickupAt))
+			if shortfalls := findShortfalls(availableStock(inventory, orders, order.ID, dueTime(order)), order.Reserved); len(shortfalls) > 0 {
+				slog.Warn("Scheduled order is short of stock", slog.String("order", order.ID), slog.Any("shortfalls", shortfalls))
+			}
+		}
+	}
+	if len(activated) == 0 {
+		return nil
+	}
+	if err := s.orderRepo.WriteJSONNewOrder(orders); err != nil {
+		return err
+	}
+	for _, i := range activated {
+		s.events.Publish(models.EventStatusChanged, orders[i])
+	}
+	return nil
+}
+
+// Starts a background loop that activates due scheduled orders every interval
+func (s *orderService) StartPickupScheduler(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if err := s.ActivateScheduledOrders(); err != nil {
+				slog.Error("Activating scheduled orders failed", slog.String("ERROR", err.Error()))
+			}
+		}
+	}()
+}
@@ -0,0 +1,127 @@
+package service
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"slices"
+	"time"
+
+	"hot-coffee/internal/dal"
+	"hot-coffee/models"
+)
+
+// Rounds an amount of money to whole cents
+func roundMoney(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
+
+// Prices the order at the current menu, promotions and tax rates and computes its totals; with keepCaptured,
+// only items without a captured price are priced and the captured discounts and taxes are kept.
+func (s *orderService) priceOrder(order *models.Order, keepCaptured bool) error {
+	for i, item := range order.Items {
+		// A reward line is paid for with loyalty points
+		if item.Reward != "" {
+			order.Items[i].UnitPrice = 0
+			continue
+		}
+		if keepCaptured && item.UnitPrice != 0 {
+			continue
+		}
+		menuItem, exists := s.orderRepo.FindMenuItem(item.ProductID)
+		if !exists {
+			return fmt.Errorf("These items are not on the menu: %s", item.ProductID)
+		}
+		price, _, err := configureItem(menuItem, item)
+		if err != nil {
+			return err
+		}
+		order.Items[i].UnitPrice = price
+	}
+	computeTotals(order)
+	if keepCaptured {
+		return nil
+	}
+	promotions, err := s.orderRepo.ReadPromotions()
+	if err != nil {
+		return err
+	}
+	if err := applyPromotions(order, promotions, time.Now()); err != nil {
+		return err
+	}
+	computeTotals(order)
+	rates, err := s.orderRepo.ReadTaxRates()
+	if err != nil {
+		return err
+	}
+	applyTaxes(order, rates, func(productID string) string {
+		menuItem, _ := s.orderRepo.FindMenuItem(productID)
+		return menuItem.Category
+	})
+	computeTotals(order)
+	return nil
+}
+
+// Captures the current menu prices on orders created before prices were captured, so their
+// value no longer follows the menu. Orders with items no longer on the menu are left as they are.
+func (s *orderService) CapturePrices() error {
+	unlock := s.orderRepo.Lock(dal.OrdersFile)
+	defer unlock()
+	orders, err := s.orderRepo.ReadJSONOrder()
+	if err != nil {
+		return err
+	}
+	priced := 0
+	for i, order := range orders {
+		if hasCapturedPrices(order) {
+			continue
+		}
+		order.Items = slices.Clone(order.Items)
+		if err := s.priceOrder(&order, true); err != nil {
+			slog.Warn("Order left unpriced", slog.String("order", order.ID), slog.String("ERROR", err.Error()))
+			continue
+		}
+		orders[i] = order
+		priced++
+	}
+	if priced == 0 {
+		return nil
+	}
+	slog.Info("Captured prices on existing orders", slog.Int("orders", priced))
+	return s.orderRepo.WriteJSONNewOrder(orders)
+}
+
+// Recomputes the line totals, subtotal, discount, tax and total from the captured unit prices, discounts and taxes
+func computeTotals(order *models.Order) {
+	subtotal, discount := 0.0, 0.0
+	for i, item := range order.Items {
+		order.Items[i].LineTotal = roundMoney(item.UnitPrice * float64(item.Quantity))
+		subtotal += order.Items[i].LineTotal
+		discount += item.Discount
+	}
+	tax, added := 0.0, 0.0
+	for _, applied := range order.Taxes {
+		tax += applied.Amount
+		if !applied.Inclusive {
+			added += applied.Amount
+		}
+	}
+	order.Subtotal = roundMoney(subtotal)
+	order.Discount = roundMoney(discount)
+	order.Tax = roundMoney(tax)
+	order.Total = roundMoney(order.Subtotal - order.Discount + added)
+}
+
+// Reports whether prices were captured on the order; orders created before prices were
+// captured have none
+func hasCapturedPrices(order models.Order) bool {
+	if order.Subtotal != 0 {
+		return true
+	}
+	for _, item := range order.Items {
+		if item.UnitPrice != 0 || item.Reward != "" {
+			return true
+		}
+	}
+	return false
+}
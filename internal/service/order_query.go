@@ -0,0 +1,160 @@
+package service
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"hot-coffee/internal/dal"
+	"hot-coffee/models"
+)
+
+// Page sizes of QueryOrders
+const (
+	DefaultOrderPageSize = 50
+	MaxOrderPageSize     = 500
+)
+
+// Layout of a date-only bound of a creation time range
+const dateLayout = "2006-01-02"
+
+// Orders compared by each supported sort field
+var orderSortFields = map[string]func(a, b models.Order) int{
+	"created_at": func(a, b models.Order) int { return strings.Compare(a.CreatedAt, b.CreatedAt) },
+	"pickup_at":  func(a, b models.Order) int { return strings.Compare(a.PickupAt, b.PickupAt) },
+	"order_id":   func(a, b models.Order) int { return compareIDs(a.ID, b.ID) },
+	"customer_name": func(a, b models.Order) int {
+		return strings.Compare(strings.ToLower(a.CustomerName), strings.ToLower(b.CustomerName))
+	},
+	"status": func(a, b models.Order) int { return strings.Compare(a.Status, b.Status) },
+	"total":  func(a, b models.Order) int { return cmp.Compare(a.Total, b.Total) },
+}
+
+// Returns one page of the orders matching the query, sorted as requested, with the number of matches
+func (s *orderService) QueryOrders(query models.OrderQuery) (models.OrderPage, error) {
+	page := models.OrderPage{Orders: []models.Order{}, Offset: query.Offset, Limit: query.Limit}
+	if page.Limit == 0 {
+		page.Limit = DefaultOrderPageSize
+	}
+	if page.Offset < 0 || page.Limit < 0 || page.Limit > MaxOrderPageSize {
+		return page, fmt.Errorf("Offset cannot be negative and limit must be between 1 and %d", MaxOrderPageSize)
+	}
+	if query.SortBy == "" {
+		query.SortBy = "created_at"
+	}
+	compare, exists := orderSortFields[query.SortBy]
+	if !exists {
+		return page, fmt.Errorf("Unknown sort field: %s", query.SortBy)
+	}
+	for _, status := range query.Statuses {
+		if !isKnownStatus(status) {
+			return page, fmt.Errorf("Unknown status: %s", status)
+		}
+	}
+	from, err := parseTimeBound(query.CreatedFrom, false)
+	if err != nil {
+		return page, err
+	}
+	to, err := parseTimeBound(query.CreatedTo, true)
+	if err != nil {
+		return page, err
+	}
+	pickupFrom, err := parseTimeBound(query.PickupFrom, false)
+	if err != nil {
+		return page, err
+	}
+	pickupTo, err := parseTimeBound(query.PickupTo, true)
+	if err != nil {
+		return page, err
+	}
+
+	unlock := s.orderRepo.RLock(dal.OrdersFile)
+	defer unlock()
+	orders, err := s.orderRepo.ReadJSONOrder()
+	if err != nil {
+		return page, err
+	}
+	matches := slices.DeleteFunc(orders, func(order models.Order) bool {
+		return !matchesOrderQuery(order, query, from, to) || !withinTimeRange(order.PickupAt, pickupFrom, pickupTo)
+	})
+	slices.SortStableFunc(matches, func(a, b models.Order) int {
+		if query.Descending {
+			return compare(b, a)
+		}
+		return compare(a, b)
+	})
+
+	page.Total = len(matches)
+	start := min(page.Offset, len(matches))
+	end := min(start+page.Limit, len(matches))
+	page.Orders = append(page.Orders, matches[start:end]...)
+	if end < len(matches) {
+		page.NextOffset = &end
+	}
+	return page, nil
+}
+
+// Reports whether an order passes the filters of a query; zero times leave the creation time unbounded
+func matchesOrderQuery(order models.Order, query models.OrderQuery, from, to time.Time) bool {
+	if len(query.Statuses) > 0 && !slices.Contains(query.Statuses, order.Status) {
+		return false
+	}
+	if name := strings.TrimSpace(query.CustomerName); name != "" && !strings.EqualFold(strings.TrimSpace(order.CustomerName), name) {
+		return false
+	}
+	if query.CustomerID != "" && order.CustomerID != query.CustomerID {
+		return false
+	}
+	if query.ProductID != "" && !slices.ContainsFunc(order.Items, func(item models.OrderItem) bool {
+		return item.ProductID == query.ProductID
+	}) {
+		return false
+	}
+	return withinTimeRange(order.CreatedAt, from, to)
+}
+
+// Reports whether a time in models.TimeLayout lies in a range; zero bounds leave the range open,
+// and with any bound set an empty or invalid time is out of range
+func withinTimeRange(value string, from, to time.Time) bool {
+	if from.IsZero() && to.IsZero() {
+		return true
+	}
+	t, err := time.ParseInLocation(models.TimeLayout, value, time.Local)
+	if err != nil {
+		return false
+	}
+	return (from.IsZero() || !t.Before(from)) && (to.IsZero() || !t.After(to))
+}
+
+// Parses a bound of a creation time range, given as a date or in models.TimeLayout. A date
+// used as the end of the range covers the whole day.
+func parseTimeBound(value string, end bool) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.ParseInLocation(models.TimeLayout, value, time.Local); err == nil {
+		return t, nil
+	}
+	t, err := time.ParseInLocation(dateLayout, value, time.Local)
+	if err != nil {
+		return time.Time{}, errors.New("Invalid time " + strconv.Quote(value) + ", expected YYYY-MM-DD or YYYY-MM-DD HH:MM:SS")
+	}
+	if end {
+		t = t.AddDate(0, 0, 1).Add(-time.Second)
+	}
+	return t, nil
+}
+
+// Compares order IDs numerically when both are numbers, so sequence IDs sort in issue order
+func compareIDs(a, b string) int {
+	x, errA := strconv.Atoi(a)
+	y, errB := strconv.Atoi(b)
+	if errA == nil && errB == nil {
+		return cmp.Compare(x, y)
+	}
+	return strings.Compare(a, b)
+}
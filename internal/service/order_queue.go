@@ -0,0 +1,58 @@
+package service
+
+import (
+	"sort"
+	"time"
+
+	"hot-coffee/internal/dal"
+	"hot-coffee/models"
+)
+
+// Statuses of the orders the bar still has to prepare or hand out
+var queueStatuses = map[string]bool{
+	models.StatusOpen:          true,
+	models.StatusAccepted:      true,
+	models.StatusInPreparation: true,
+	models.StatusReady:         true,
+}
+
+// Returns the orders waiting at the bar in the order they should be prepared: by pickup time
+// for pre-orders and by creation time otherwise, the oldest first
+func (s *orderService) Queue() ([]models.Order, error) {
+	unlock := s.orderRepo.RLock(dal.OrdersFile)
+	defer unlock()
+	orders, err := s.orderRepo.ReadJSONOrder()
+	if err != nil {
+		return nil, err
+	}
+	queue := make([]models.Order, 0, len(orders))
+	for _, order := range orders {
+		if queueStatuses[order.Status] {
+			queue = append(queue, order)
+		}
+	}
+	sort.SliceStable(queue, func(i, j int) bool {
+		a, b := dueTime(queue[i]), dueTime(queue[j])
+		if !a.Equal(b) {
+			return a.Before(b)
+		}
+		return compareIDs(queue[i].ID, queue[j].ID) < 0
+	})
+	return queue, nil
+}
+
+// Returns when an order is due: its pickup time if it has one, otherwise its creation time
+func dueTime(order models.Order) time.Time {
+	if order.PickupAt != "" {
+		if pickup, err := time.ParseInLocation(models.TimeLayout, order.PickupAt, time.Local); err == nil {
+			return pickup
+		}
+	}
+	created, _ := time.ParseInLocation(models.TimeLayout, order.CreatedAt, time.Local)
+	return created
+}
+
+// Subscribes to the events of every saved change to an order
+func (s *orderService) Subscribe() (<-chan models.OrderEvent, func()) {
+	return s.events.Subscribe()
+}
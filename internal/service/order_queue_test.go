@@ -0,0 +1,88 @@
+package service
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"hot-coffee/models"
+)
+
+// The queue holds the orders still to be prepared or handed out, pre-orders by pickup time and
+// the others by creation time, ties broken by ID
+func TestQueue(t *testing.T) {
+	orders, store := newTestOrderService(t)
+	base := time.Now().Add(-time.Hour)
+	at := func(minutes int) string {
+		return base.Add(time.Duration(minutes) * time.Minute).Format(models.TimeLayout)
+	}
+	tx := store.Begin()
+	if err := tx.StageOrders([]models.Order{
+		{ID: "1", CustomerName: "Ada", Status: models.StatusOpen, CreatedAt: at(10)},
+		{ID: "2", CustomerName: "Ada", Status: models.StatusReady, CreatedAt: at(0)},
+		{ID: "3", CustomerName: "Ada", Status: models.StatusAccepted, CreatedAt: at(0), PickupAt: at(5)},
+		{ID: "10", CustomerName: "Ada", Status: models.StatusInPreparation, CreatedAt: at(10)},
+		{ID: "4", CustomerName: "Ada", Status: models.StatusScheduled, CreatedAt: at(0), PickupAt: at(600)},
+		{ID: "5", CustomerName: "Ada", Status: models.StatusPickedUp, CreatedAt: at(0)},
+		{ID: "6", CustomerName: "Ada", Status: models.StatusClosed, CreatedAt: at(0)},
+		{ID: "7", CustomerName: "Ada", Status: models.StatusCancelled, CreatedAt: at(0)},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	queue, err := orders.Queue()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, order := range queue {
+		ids = append(ids, order.ID)
+	}
+	if want := []string{"2", "3", "1", "10"}; !slices.Equal(ids, want) {
+		t.Errorf("queue is %v, want %v", ids, want)
+	}
+}
+
+// Every saved change to an order is published with the order after the change
+func TestOrderEvents(t *testing.T) {
+	orders, _ := newTestOrderService(t)
+	events, unsubscribe := orders.Subscribe()
+	defer unsubscribe()
+	created, err := orders.ServicePostOrders(latteRequest("Ada", 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := orders.TransitionOrder(created.ID, models.StatusAccepted); err != nil {
+		t.Fatal(err)
+	}
+	if err := orders.TransitionOrder(created.ID, models.StatusReady); err == nil {
+		t.Fatal("illegal transition succeeded")
+	}
+	if _, err := orders.PayOrder(created.ID, []models.Payment{{Method: models.TenderCard, Amount: created.Total}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := orders.CloseOrder(created.ID); err != nil {
+		t.Fatal(err)
+	}
+	want := []struct{ eventType, status string }{
+		{models.EventOrderCreated, models.StatusOpen},
+		{models.EventStatusChanged, models.StatusAccepted},
+		{models.EventOrderPaid, models.StatusAccepted},
+		{models.EventOrderClosed, models.StatusClosed},
+	}
+	for _, w := range want {
+		select {
+		case event := <-events:
+			if event.Type != w.eventType || event.Order.ID != created.ID || event.Order.Status != w.status {
+				t.Errorf("got %s for order %s (%s), want %s (%s)", event.Type, event.Order.ID, event.Order.Status, w.eventType, w.status)
+			}
+		default:
+			t.Fatalf("no %s event", w.eventType)
+		}
+	}
+	if extra := pendingEvents(events); len(extra) != 0 {
+		t.Errorf("unexpected events %v", extra)
+	}
+}
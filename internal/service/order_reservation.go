@@ -0,0 +1,199 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"hot-coffee/models"
+)
+
+// Rounds an ingredient amount so repeated reserving and releasing does not accumulate float error
+func roundAmount(amount float64) float64 {
+	return math.Round(amount*1e6) / 1e6
+}
+
+// Returns the amount of each ingredient needed to prepare the order's items with the current
+// recipes and the chosen variants and modifiers
+func (s *orderService) requiredIngredients(order models.Order) (map[string]float64, error) {
+	need := make(map[string]float64)
+	missing := []string{}
+	for _, item := range order.Items {
+		menuItem, exists := s.orderRepo.FindMenuItem(item.ProductID)
+		if !exists {
+			missing = append(missing, item.ProductID)
+			continue
+		}
+		_, recipe, err := configureItem(menuItem, item)
+		if err != nil {
+			return nil, err
+		}
+		for id, amount := range recipe {
+			need[id] += amount * float64(item.Quantity)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("These items are not on the menu: %s", strings.Join(missing, ", "))
+	}
+	for id, amount := range need {
+		need[id] = roundAmount(amount)
+	}
+	return need, nil
+}
+
+// Returns the position of each ingredient in the inventory
+func indexInventory(inventory []models.InventoryItem) map[string]int {
+	index := make(map[string]int, len(inventory))
+	for i, item := range inventory {
+		index[item.IngredientID] = i
+	}
+	return index
+}
+
+// Returns the stock of each ingredient an order due at the given time can draw on: the stock
+// on hand less what the other active orders due by then have reserved. Reservations of
+// pre-orders due later are left out, since the stock is restocked before they are due.
+func availableStock(inventory []models.InventoryItem, orders []models.Order, orderID string, due time.Time) map[string]float64 {
+	available := make(map[string]float64, len(inventory))
+	for _, item := range inventory {
+		available[item.IngredientID] = item.Quantity
+	}
+	for _, order := range orders {
+		if order.ID == orderID || !isActiveStatus(order.Status) || dueTime(order).After(due) {
+			continue
+		}
+		for id, amount := range order.Reserved {
+			if _, exists := available[id]; exists {
+				available[id] -= amount
+			}
+		}
+	}
+	for id, amount := range available {
+		available[id] = max(0, roundAmount(amount))
+	}
+	return available
+}
+
+// Returns when an order draws on the stock: at its pickup time if that is still ahead, otherwise now
+func stockDue(order models.Order, now time.Time) time.Time {
+	if due := dueTime(order); due.After(now) {
+		return due
+	}
+	return now
+}
+
+// Reserves the given ingredients for the order, failing without changes if the stock
+// available to it is too low
+func reserveIngredients(inventory []models.InventoryItem, available map[string]float64, order *models.Order, need map[string]float64) error {
+	index := indexInventory(inventory)
+	missing, short := []string{}, []string{}
+	for _, id := range slices.Sorted(maps.Keys(need)) {
+		if _, exists := index[id]; !exists {
+			missing = append(missing, id)
+			continue
+		}
+		if available[id] < need[id] {
+			short = append(short, id)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("These items are not in the inventory: %s", strings.Join(missing, ", "))
+	}
+	if len(short) > 0 {
+		return fmt.Errorf("Not enough ingredients: %s", strings.Join(short, ", "))
+	}
+	for id, amount := range need {
+		i := index[id]
+		inventory[i].Reserved = roundAmount(inventory[i].Reserved + amount)
+	}
+	order.Reserved = need
+	return nil
+}
+
+// Returns the ingredients whose available stock is below the amount needed, sorted by
+// ingredient ID; ingredients missing from the inventory have nothing available
+func findShortfalls(available map[string]float64, need map[string]float64) []models.Shortfall {
+	shortfalls := []models.Shortfall{}
+	for _, id := range slices.Sorted(maps.Keys(need)) {
+		if available[id] < need[id] {
+			shortfalls = append(shortfalls, models.Shortfall{
+				IngredientID: id,
+				Required:     need[id],
+				Available:    available[id],
+				Missing:      roundAmount(need[id] - available[id]),
+			})
+		}
+	}
+	return shortfalls
+}
+
+// Gives back the ingredients reserved for the order
+func releaseIngredients(inventory []models.InventoryItem, order *models.Order) {
+	index := indexInventory(inventory)
+	for id, amount := range order.Reserved {
+		if i, exists := index[id]; exists {
+			inventory[i].Reserved = max(0, roundAmount(inventory[i].Reserved-amount))
+		}
+	}
+	order.Reserved = nil
+}
+
+// Turns the order's reservation into a deduction from the stock on hand and records what
+// was deducted. Orders placed before reservations existed reserve their ingredients first,
+// from the stock not held by the other orders due by now.
+func (s *orderService) consumeIngredients(inventory []models.InventoryItem, orders []models.Order, order *models.Order) error {
+	if order.Reserved == nil {
+		need, err := s.requiredIngredients(*order)
+		if err != nil {
+			return err
+		}
+		if err := reserveIngredients(inventory, availableStock(inventory, orders, order.ID, stockDue(*order, time.Now())), order, need); err != nil {
+			return err
+		}
+	}
+	index := indexInventory(inventory)
+	for id, amount := range order.Reserved {
+		i, exists := index[id]
+		if !exists {
+			return errors.New("These items are not in the inventory: " + id)
+		}
+		inventory[i].Quantity = max(0, roundAmount(inventory[i].Quantity-amount))
+	}
+	consumed := order.Reserved
+	releaseIngredients(inventory, order)
+	order.Consumed = consumed
+	return nil
+}
+
+// Returns the ingredients deducted for a closed order to the stock on hand. Orders closed
+// before deductions were recorded give back what their items need with the current recipes.
+func (s *orderService) restockIngredients(inventory []models.InventoryItem, order *models.Order) error {
+	amounts := order.Consumed
+	if amounts == nil {
+		need, err := s.requiredIngredients(*order)
+		if err != nil {
+			return err
+		}
+		amounts = need
+	}
+	index := indexInventory(inventory)
+	missing := []string{}
+	for _, id := range slices.Sorted(maps.Keys(amounts)) {
+		if _, exists := index[id]; !exists {
+			missing = append(missing, id)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("These items are not in the inventory: %s", strings.Join(missing, ", "))
+	}
+	for id, amount := range amounts {
+		i := index[id]
+		inventory[i].Quantity = roundAmount(inventory[i].Quantity + amount)
+	}
+	order.Restocked = true
+	return nil
+}
@@ -0,0 +1,216 @@
+package service
+
+import (
+	"maps"
+	"slices"
+	"testing"
+	"time"
+
+	"hot-coffee/internal/dal"
+	"hot-coffee/models"
+)
+
+// Returns an inventory of shots and milk with the given amounts on hand and reserved
+func testInventory(shots, shotsReserved, milk, milkReserved float64) []models.InventoryItem {
+	return []models.InventoryItem{
+		{IngredientID: "espresso_shot", Name: "Espresso Shot", Quantity: shots, Reserved: shotsReserved, Unit: "shots"},
+		{IngredientID: "milk", Name: "Milk", Quantity: milk, Reserved: milkReserved, Unit: "ml"},
+	}
+}
+
+// Only the reservations of the active orders due by the given time reduce the available stock
+func TestAvailableStock(t *testing.T) {
+	now := time.Date(2026, 3, 2, 8, 0, 0, 0, time.Local)
+	at := func(hour int) string {
+		return now.Add(time.Duration(hour) * time.Hour).Format(models.TimeLayout)
+	}
+	shots := func(amount float64) map[string]float64 {
+		return map[string]float64{"espresso_shot": amount}
+	}
+	inventory := testInventory(10, 9, 1000, 0)
+	orders := []models.Order{
+		{ID: "1", Status: models.StatusOpen, CreatedAt: at(-1), Reserved: shots(1)},
+		{ID: "2", Status: models.StatusReady, CreatedAt: at(-2), Reserved: shots(2)},
+		{ID: "3", Status: models.StatusScheduled, CreatedAt: at(-3), PickupAt: at(1), Reserved: shots(3)},
+		{ID: "4", Status: models.StatusScheduled, CreatedAt: at(-3), PickupAt: at(3), Reserved: shots(3)},
+		{ID: "5", Status: models.StatusClosed, CreatedAt: at(-4), Consumed: shots(4)},
+		{ID: "6", Status: models.StatusCancelled, CreatedAt: at(-4), Reserved: shots(4)},
+	}
+	tests := []struct {
+		name    string
+		orderID string
+		due     time.Time
+		want    float64
+	}{
+		{"now", "", now, 7},
+		{"at the first pickup", "", now.Add(time.Hour), 4},
+		{"between the pickups", "", now.Add(2 * time.Hour), 4},
+		{"after every pickup", "", now.Add(4 * time.Hour), 1},
+		{"leaving out the order itself", "3", now.Add(4 * time.Hour), 4},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			available := availableStock(inventory, orders, test.orderID, test.due)
+			if available["espresso_shot"] != test.want || available["milk"] != 1000 {
+				t.Errorf("available stock is %v, want %v shots and 1000 milk", available, test.want)
+			}
+		})
+	}
+	// Orders holding more than the stock on hand leave nothing, not a negative amount
+	if available := availableStock(testInventory(2, 0, 0, 0), orders, "", now); available["espresso_shot"] != 0 {
+		t.Errorf("overcommitted stock is %v, want 0", available["espresso_shot"])
+	}
+}
+
+// An order draws on the stock at its pickup time while that is ahead, otherwise now
+func TestStockDue(t *testing.T) {
+	now := time.Date(2026, 3, 2, 8, 0, 0, 0, time.Local)
+	tests := []struct {
+		order models.Order
+		want  time.Time
+	}{
+		{models.Order{}, now},
+		{models.Order{CreatedAt: now.Add(-time.Hour).Format(models.TimeLayout)}, now},
+		{models.Order{PickupAt: now.Add(-time.Hour).Format(models.TimeLayout)}, now},
+		{models.Order{PickupAt: now.Add(time.Hour).Format(models.TimeLayout)}, now.Add(time.Hour)},
+	}
+	for _, test := range tests {
+		if got := stockDue(test.order, now); !got.Equal(test.want) {
+			t.Errorf("stockDue(%+v) = %v, want %v", test.order, got, test.want)
+		}
+	}
+}
+
+func TestReserveIngredients(t *testing.T) {
+	tests := []struct {
+		name      string
+		available map[string]float64
+		need      map[string]float64
+		wantErr   bool
+		want      []models.InventoryItem
+	}{
+		{
+			"enough available stock",
+			map[string]float64{"espresso_shot": 8, "milk": 600}, map[string]float64{"espresso_shot": 2, "milk": 400},
+			false, testInventory(10, 4, 1000, 800),
+		},
+		{
+			"exactly the available stock",
+			map[string]float64{"espresso_shot": 2, "milk": 400}, map[string]float64{"espresso_shot": 2, "milk": 400},
+			false, testInventory(10, 4, 1000, 800),
+		},
+		{
+			"stock taken by orders due earlier",
+			map[string]float64{"espresso_shot": 0.5, "milk": 1000}, map[string]float64{"espresso_shot": 1, "milk": 200},
+			true, testInventory(10, 2, 1000, 400),
+		},
+		{
+			"ingredient not in the inventory",
+			map[string]float64{"espresso_shot": 10, "milk": 1000, "syrup": 10}, map[string]float64{"espresso_shot": 1, "syrup": 10},
+			true, testInventory(10, 2, 1000, 400),
+		},
+		{
+			"repeated fractions do not drift",
+			map[string]float64{"espresso_shot": 1, "milk": 1000}, map[string]float64{"espresso_shot": 0.1},
+			false, testInventory(10, 2.1, 1000, 400),
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			inventory := testInventory(10, 2, 1000, 400)
+			order := models.Order{}
+			err := reserveIngredients(inventory, test.available, &order, test.need)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("error %v, want error %v", err, test.wantErr)
+			}
+			for i := range test.want {
+				if inventory[i] != test.want[i] {
+					t.Errorf("inventory is %+v, want %+v", inventory[i], test.want[i])
+				}
+			}
+			if test.wantErr && order.Reserved != nil {
+				t.Errorf("failed reservation recorded %v on the order", order.Reserved)
+			}
+			if !test.wantErr && !maps.Equal(order.Reserved, test.need) {
+				t.Errorf("order reserves %v, want %v", order.Reserved, test.need)
+			}
+		})
+	}
+}
+
+// Releasing gives back exactly what was reserved and never drives the reservation negative
+func TestReleaseIngredients(t *testing.T) {
+	inventory := testInventory(10, 3, 1000, 100)
+	order := models.Order{Reserved: map[string]float64{"espresso_shot": 2, "milk": 200, "syrup": 5}}
+	releaseIngredients(inventory, &order)
+	if want := testInventory(10, 1, 1000, 0); inventory[0] != want[0] || inventory[1] != want[1] {
+		t.Errorf("inventory is %+v, want %+v", inventory, want)
+	}
+	if order.Reserved != nil {
+		t.Errorf("order still reserves %v", order.Reserved)
+	}
+}
+
+func TestConsumeIngredients(t *testing.T) {
+	store, _ := newTestStore(t)
+	s := &orderService{orderRepo: dal.NewJSONOrderRepository(store, dal.NewFileLocks())}
+	latte := []models.OrderItem{{ProductID: "latte", Quantity: 2}}
+	tests := []struct {
+		name      string
+		inventory []models.InventoryItem
+		order     models.Order
+		wantErr   bool
+		want      []models.InventoryItem
+	}{
+		{
+			"reserved order",
+			testInventory(10, 3, 1000, 400), models.Order{Items: latte, Reserved: map[string]float64{"espresso_shot": 2, "milk": 400}},
+			false, testInventory(8, 1, 600, 0),
+		},
+		{
+			"order placed before reservations",
+			testInventory(10, 0, 1000, 0), models.Order{Items: latte},
+			false, testInventory(8, 0, 600, 0),
+		},
+		{
+			"order placed before reservations without the stock",
+			testInventory(1, 0, 1000, 0), models.Order{Items: latte},
+			true, testInventory(1, 0, 1000, 0),
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := s.consumeIngredients(test.inventory, nil, &test.order)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("error %v, want error %v", err, test.wantErr)
+			}
+			for i := range test.want {
+				if test.inventory[i] != test.want[i] {
+					t.Errorf("inventory is %+v, want %+v", test.inventory[i], test.want[i])
+				}
+			}
+			if test.wantErr {
+				return
+			}
+			if want := map[string]float64{"espresso_shot": 2, "milk": 400}; !maps.Equal(test.order.Consumed, want) {
+				t.Errorf("order consumed %v, want %v", test.order.Consumed, want)
+			}
+			if test.order.Reserved != nil {
+				t.Errorf("order still reserves %v", test.order.Reserved)
+			}
+		})
+	}
+}
+
+// Shortfalls report what is missing of each ingredient beyond the available stock
+func TestFindShortfalls(t *testing.T) {
+	available := map[string]float64{"espresso_shot": 1, "milk": 1000}
+	got := findShortfalls(available, map[string]float64{"espresso_shot": 2, "milk": 200, "syrup": 5})
+	want := []models.Shortfall{
+		{IngredientID: "espresso_shot", Required: 2, Available: 1, Missing: 1},
+		{IngredientID: "syrup", Required: 5, Available: 0, Missing: 5},
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("findShortfalls = %+v, want %+v", got, want)
+	}
+}
@@ -10,58 +10,251 @@ import (
 	"hot-coffee/models"
 )
 
+// ErrStorage marks failures to read or write the data files, as opposed to requests that are invalid
+var ErrStorage = errors.New("Failed to access the data files")
+
 type OrderService interface {
-	ServicePostOrders(body models.Order) error
+	ServicePostOrders(request models.OrderRequest) (models.Order, error)
 	ServicePutOrderID(id string, newEdit models.Order) error
 	CloseOrder(id string) error
+	PayOrder(id string, tenders []models.Payment) (models.OrderPayments, error)
+	OrderPayments(id string) (models.OrderPayments, error)
+	TransitionOrder(id string, status string) error
+	CancelOrder(id string, reason string) error
+	RefundOrder(id string, reason string, restock bool) error
 	ServiceDeleteOrdersID(id string) error
 	GetOrdersService() ([]models.Order, error)
+	QueryOrders(query models.OrderQuery) (models.OrderPage, error)
+	QuoteOrder(request models.OrderRequest) (models.Quote, error)
+	ActivateScheduledOrders() error
+	StartPickupScheduler(interval time.Duration)
 	GetIDOrdersService(id string) (models.Order, error)
 	IsItOnTheMenu(body models.Order) error
+	CapturePrices() error
+	Queue() ([]models.Order, error)
+	Subscribe() (events <-chan models.OrderEvent, unsubscribe func())
 }
 
 type orderService struct {
-	orderRepo dal.OrderRepository
+	orderRepo          dal.OrderRepository
+	idGen              IDGenerator
+	maxOpenPerCustomer int           // Active orders allowed per customer; 0 means no limit.
+	pickupLead         time.Duration // Time before pickup a scheduled order becomes open.
+	events             EventBus      // Receives every saved change to an order.
 }
 
-var Id int
-
-// Initializes and returns a new instance of orderService with the provided repository
-func NewOrderService(orderRepo dal.OrderRepository) OrderService {
-	return &orderService{orderRepo: orderRepo}
+// Initializes and returns a new instance of orderService with the provided repository, ID generator,
+// per-customer limit of active orders (0 for no limit), lead time of scheduled orders and the bus order events are published to
+func NewOrderService(orderRepo dal.OrderRepository, idGen IDGenerator, maxOpenPerCustomer int, pickupLead time.Duration, events EventBus) OrderService {
+	return &orderService{orderRepo: orderRepo, idGen: idGen, maxOpenPerCustomer: maxOpenPerCustomer, pickupLead: pickupLead, events: events}
 }
 
-// Creates a new order, validates the order details, and ensures no open orders exist
-func (s orderService) ServicePostOrders(body models.Order) error {
+// Creates a new order, validates the order details, enforces the customer's limit of active
+// orders, reserves the ingredients the order needs and debits the loyalty rewards it takes.
+// It returns the created order.
+func (s orderService) ServicePostOrders(request models.OrderRequest) (models.Order, error) {
+	unlock := s.orderRepo.Lock(dal.InventoryitemFile, dal.MenuItemFile, dal.OrdersFile, dal.CustomersFile, dal.LoyaltyRulesFile, dal.LoyaltyLedgerFile, dal.PromotionsFile, dal.TaxRatesFile)
+	defer unlock()
+	body := orderFromRequest(request)
+	if err := s.linkCustomer(&body); err != nil {
+		return models.Order{}, err
+	}
 	if err := checkBodyOrder(body); err != nil {
-		return err
+		return models.Order{}, err
+	}
+	if err := checkRewards(body, s.orderRepo.FindLoyaltyRule); err != nil {
+		return models.Order{}, err
 	}
 	if err := s.IsItOnTheMenu(body); err != nil {
-		return err
+		return models.Order{}, err
 	}
 	listOrder, err := s.orderRepo.ReadJSONOrder()
 	if err != nil {
-		return err
+		return models.Order{}, fmt.Errorf("%w: %v", ErrStorage, err)
+	}
+	if s.maxOpenPerCustomer > 0 && countActiveOrders(listOrder, body) >= s.maxOpenPerCustomer {
+		return models.Order{}, fmt.Errorf("You already have %d open orders", s.maxOpenPerCustomer)
+	}
+	if err := s.priceOrder(&body, false); err != nil {
+		return models.Order{}, err
 	}
-	body.ID = orderNumberCreator()
-	for _, oneOrder := range listOrder {
-		if oneOrder.Status == "open" {
-			return errors.New("You already have an open order")
+	inventory, err := s.orderRepo.ReadJSONInv()
+	if err != nil {
+		return models.Order{}, fmt.Errorf("%w: %v", ErrStorage, err)
+	}
+	need, err := s.requiredIngredients(body)
+	if err != nil {
+		return models.Order{}, err
+	}
+	nowTime := time.Now()
+	status := models.StatusOpen
+	if body.PickupAt != "" {
+		pickup, err := parsePickupTime(body.PickupAt, nowTime)
+		if err != nil {
+			return models.Order{}, err
 		}
-		if oneOrder.ID == body.ID {
-			body.ID = orderNumberCreator()
+		body.PickupAt = pickup.Format(models.TimeLayout)
+		// Pre-orders hold their ingredients from now on but wait outside the queue until shortly before pickup
+		if !s.pickupDue(body.PickupAt, nowTime) {
+			status = models.StatusScheduled
 		}
 	}
-	body.Status = "open"
-	nowTime := time.Now()
-	timeString := nowTime.Format("2006-01-02 15:04:05")
-	body.CreatedAt = timeString
+	if err := reserveIngredients(inventory, availableStock(inventory, listOrder, "", stockDue(body, nowTime)), &body, need); err != nil {
+		return models.Order{}, err
+	}
+	ledger, err := s.orderRepo.ReadLedger()
+	if err != nil {
+		return models.Order{}, fmt.Errorf("%w: %v", ErrStorage, err)
+	}
+	newLedger, err := redeemRewards(ledger, body, s.orderRepo.FindLoyaltyRule, nowTime)
+	if err != nil {
+		return models.Order{}, err
+	}
+	// The ID is taken only once the order is known to be valid, so rejected orders leave no gaps
+	body.ID, err = s.newOrderID()
+	if err != nil {
+		return models.Order{}, err
+	}
+	for i := len(ledger); i < len(newLedger); i++ {
+		newLedger[i].OrderID = body.ID
+	}
+	setStatus(&body, status, nowTime)
+	body.CreatedAt = nowTime.Format(models.TimeLayout)
 	listOrder = append(listOrder, body)
 
-	if err := s.orderRepo.WriteJSONNewOrder(listOrder); err != nil {
+	if err := s.saveOrdersAndInventory(listOrder, inventory, changedLedger(ledger, newLedger)); err != nil {
+		return models.Order{}, err
+	}
+	s.events.Publish(models.EventOrderCreated, body)
+	return body, nil
+}
+
+// Returns a new order holding only the fields a client may set
+func orderFromRequest(request models.OrderRequest) models.Order {
+	return models.Order{
+		CustomerName: request.CustomerName,
+		CustomerID:   request.CustomerID,
+		ServiceType:  request.ServiceType,
+		Items:        request.Items,
+		PickupAt:     request.PickupAt,
+		PromoCodes:   request.PromoCodes,
+	}
+}
+
+// Validates and prices a prospective order and checks the stock available to it at its pickup
+// time, or now, without saving anything
+func (s *orderService) QuoteOrder(request models.OrderRequest) (models.Quote, error) {
+	unlock := s.orderRepo.RLock(dal.InventoryitemFile, dal.MenuItemFile, dal.OrdersFile, dal.CustomersFile, dal.LoyaltyRulesFile, dal.PromotionsFile, dal.TaxRatesFile)
+	defer unlock()
+	body := orderFromRequest(request)
+	if err := s.linkCustomer(&body); err != nil {
+		return models.Quote{}, err
+	}
+	if err := checkBodyOrder(body); err != nil {
+		return models.Quote{}, err
+	}
+	if err := checkRewards(body, s.orderRepo.FindLoyaltyRule); err != nil {
+		return models.Quote{}, err
+	}
+	if err := s.IsItOnTheMenu(body); err != nil {
+		return models.Quote{}, err
+	}
+	if err := s.priceOrder(&body, false); err != nil {
+		return models.Quote{}, err
+	}
+	need, err := s.requiredIngredients(body)
+	if err != nil {
+		return models.Quote{}, err
+	}
+	now := time.Now()
+	if body.PickupAt != "" {
+		pickup, err := parsePickupTime(body.PickupAt, now)
+		if err != nil {
+			return models.Quote{}, err
+		}
+		body.PickupAt = pickup.Format(models.TimeLayout)
+	}
+	orders, err := s.orderRepo.ReadJSONOrder()
+	if err != nil {
+		return models.Quote{}, err
+	}
+	inventory, err := s.orderRepo.ReadJSONInv()
+	if err != nil {
+		return models.Quote{}, err
+	}
+	shortfalls := findShortfalls(availableStock(inventory, orders, "", stockDue(body, now)), need)
+	return models.Quote{
+		Items:      body.Items,
+		Subtotal:   body.Subtotal,
+		Discounts:  body.Discounts,
+		Discount:   body.Discount,
+		Taxes:      body.Taxes,
+		Tax:        body.Tax,
+		Total:      body.Total,
+		Available:  len(shortfalls) == 0,
+		Shortfalls: shortfalls,
+	}, nil
+}
+
+// Writes orders and inventory, and the loyalty ledger unless it is nil, in one transaction, so
+// reservations and loyalty entries always match the orders they belong to
+func (s *orderService) saveOrdersAndInventory(orders []models.Order, inventory []models.InventoryItem, ledger []models.LoyaltyEntry) error {
+	uow := s.orderRepo.Begin()
+	defer uow.Rollback()
+	if err := uow.StageInventory(inventory); err != nil {
+		return err
+	}
+	if ledger != nil {
+		if err := uow.StageLedger(ledger); err != nil {
+			return err
+		}
+	}
+	if err := uow.StageOrders(orders); err != nil {
 		return err
 	}
+	if err := uow.Commit(); err != nil {
+		return fmt.Errorf("%w: %v", ErrStorage, err)
+	}
+	return nil
+}
+
+// Returns the new ledger if entries were added to it, or nil if it is unchanged and need not be written
+func changedLedger(before, after []models.LoyaltyEntry) []models.LoyaltyEntry {
+	if len(after) == len(before) {
+		return nil
+	}
+	return after
+}
 
+// Counts the active orders of the customer placing an order: by customer ID for a
+// registered customer, otherwise by name
+func countActiveOrders(orders []models.Order, body models.Order) int {
+	customerName := strings.TrimSpace(body.CustomerName)
+	count := 0
+	for _, order := range orders {
+		if !isActiveStatus(order.Status) {
+			continue
+		}
+		if body.CustomerID != "" && order.CustomerID == body.CustomerID ||
+			body.CustomerID == "" && strings.EqualFold(strings.TrimSpace(order.CustomerName), customerName) {
+			count++
+		}
+	}
+	return count
+}
+
+// Checks the customer an order is linked to and takes the customer name from them;
+// orders without a customer ID keep the name they were given
+func (s *orderService) linkCustomer(order *models.Order) error {
+	order.CustomerID = strings.TrimSpace(order.CustomerID)
+	if order.CustomerID == "" {
+		return nil
+	}
+	customer, exists := s.orderRepo.FindCustomer(order.CustomerID)
+	if !exists {
+		return ErrCustomerNotFound
+	}
+	order.CustomerName = customer.Name
 	return nil
 }
 
@@ -84,27 +277,49 @@ func checkBodyOrder(body models.Order) error {
 		}
 
 	}
+	if !isKnownServiceType(body.ServiceType) {
+		return fmt.Errorf("Unknown service type %q, expected %s or %s", body.ServiceType, models.ServiceDineIn, models.ServiceTakeaway)
+	}
 	return nil
 }
 
-// Generates a unique order ID by incrementing a counter
-func orderNumberCreator() string {
-	var id string
-	Id++
-	id = fmt.Sprintf("%d", Id)
-	return id
+// Issues a new order ID, skipping any ID already taken by an order created outside the generator
+func (s *orderService) newOrderID() (string, error) {
+	for {
+		id, err := s.idGen.NextID()
+		if err != nil {
+			return "", fmt.Errorf("%w: %v", ErrStorage, err)
+		}
+		if _, exists := s.orderRepo.FindOrder(id); !exists {
+			return id, nil
+		}
+	}
 }
 
-// Updates an existing order by ID, ensuring it is still open and validating the new data
+// Updates an existing order by ID, ensuring it is still open or scheduled, validating the new
+// data and adjusting the ingredients reserved and the loyalty rewards debited for it; only scheduled
+// orders can change their pickup time
 func (s *orderService) ServicePutOrderID(id string, body models.Order) error {
+	unlock := s.orderRepo.Lock(dal.InventoryitemFile, dal.MenuItemFile, dal.OrdersFile, dal.CustomersFile, dal.LoyaltyRulesFile, dal.LoyaltyLedgerFile, dal.PromotionsFile, dal.TaxRatesFile)
+	defer unlock()
 	if err := s.IsItOnTheMenu(body); err != nil {
 		return err
 	}
+	var updated models.Order
 	checker := false
 	jsonfilemenu, err := s.orderRepo.ReadJSONOrder()
 	if err != nil {
 		return err
 	}
+	inventory, err := s.orderRepo.ReadJSONInv()
+	if err != nil {
+		return err
+	}
+	ledger, err := s.orderRepo.ReadLedger()
+	if err != nil {
+		return err
+	}
+	newLedger := ledger
 	for i, oneStructure := range jsonfilemenu {
 		if oneStructure.ID == id {
 
@@ -113,22 +328,62 @@ func (s *orderService) ServicePutOrderID(id string, body models.Order) error {
 			if err != nil {
 				return err
 			}
+			if err := s.linkCustomer(&newEditedStructure); err != nil {
+				return err
+			}
 			if err := checkBodyOrder(newEditedStructure); err != nil {
 				return err
 			}
-			if newEditedStructure.Status != "open" {
-				return errors.New("Order with this ID closed")
+			if err := checkRewards(newEditedStructure, s.orderRepo.FindLoyaltyRule); err != nil {
+				return err
+			}
+			if newEditedStructure.Status != models.StatusOpen && newEditedStructure.Status != models.StatusScheduled {
+				return errors.New("Only open or scheduled orders can be updated")
+			}
+			if len(newEditedStructure.Payments) > 0 {
+				return errors.New("Orders with payments cannot be updated")
+			}
+			if body.PickupAt != "" {
+				if newEditedStructure.Status != models.StatusScheduled {
+					return errors.New("Only the pickup time of scheduled orders can be changed")
+				}
+				pickup, err := parsePickupTime(body.PickupAt, time.Now())
+				if err != nil {
+					return err
+				}
+				newEditedStructure.PickupAt = pickup.Format(models.TimeLayout)
+			}
+			if err := s.priceOrder(&newEditedStructure, false); err != nil {
+				return err
+			}
+			need, err := s.requiredIngredients(newEditedStructure)
+			if err != nil {
+				return err
+			}
+			releaseIngredients(inventory, &newEditedStructure)
+			available := availableStock(inventory, jsonfilemenu, id, stockDue(newEditedStructure, time.Now()))
+			if err := reserveIngredients(inventory, available, &newEditedStructure, need); err != nil {
+				return err
+			}
+			// The rewards of the previous version are given back before those of the new one are taken
+			now := time.Now()
+			newLedger = reverseOrderEntries(ledger, id, "Order updated", now)
+			newLedger, err = redeemRewards(newLedger, newEditedStructure, s.orderRepo.FindLoyaltyRule, now)
+			if err != nil {
+				return err
 			}
 			jsonfilemenu[i] = newEditedStructure
+			updated = newEditedStructure
 		}
 	}
 
 	if !checker {
 		return errors.New("ID not found")
 	}
-	if err := s.orderRepo.WriteJSONNewOrder(jsonfilemenu); err != nil {
+	if err := s.saveOrdersAndInventory(jsonfilemenu, inventory, changedLedger(ledger, newLedger)); err != nil {
 		return err
 	}
+	s.events.Publish(models.EventOrderUpdated, updated)
 	return nil
 }
 
@@ -140,6 +395,15 @@ func (s *orderService) EditStructureOrder(newOrder models.Order, oldOrder models
 	if newOrderName != "" {
 		newEditedStructure.CustomerName = newOrder.CustomerName
 	}
+	if strings.TrimSpace(newOrder.CustomerID) != "" {
+		newEditedStructure.CustomerID = newOrder.CustomerID
+	}
+	if newOrder.PromoCodes != nil {
+		newEditedStructure.PromoCodes = newOrder.PromoCodes
+	}
+	if newOrder.ServiceType != "" {
+		newEditedStructure.ServiceType = newOrder.ServiceType
+	}
 	newEditedStructure.Items = newOrder.Items
 	err := checkBodyOrder(newEditedStructure)
 	if err != nil {
@@ -149,59 +413,174 @@ func (s *orderService) EditStructureOrder(newOrder models.Order, oldOrder models
 	return nil, newEditedStructure
 }
 
-// Closes an open order by ID, updates inventory quantities, and writes changes
+// Closes a fully paid active order by ID, turning its reservation into a deduction from the inventory
+// and crediting the customer with the loyalty points it earns
 func (s *orderService) CloseOrder(id string) error {
+	unlock := s.orderRepo.Lock(dal.InventoryitemFile, dal.MenuItemFile, dal.OrdersFile, dal.LoyaltyRulesFile, dal.LoyaltyLedgerFile)
+	defer unlock()
 	orders, err := s.orderRepo.ReadJSONOrder()
 	if err != nil {
 		return err
 	}
-	closeOrder := models.Order{}
-	checker := false
-	for _, oneOrder := range orders {
-		if oneOrder.ID == id {
-			if oneOrder.Status == "open" {
-				checker = true
-				closeOrder = oneOrder
-
-			} else {
-				return errors.New("Status not open")
-			}
+	inventory, err := s.orderRepo.ReadJSONInv()
+	if err != nil {
+		return err
+	}
+	closed := -1
+	for i, oneOrder := range orders {
+		if oneOrder.ID != id {
+			continue
+		}
+		if err := checkTransition(oneOrder.Status, models.StatusClosed); err != nil {
+			return err
 		}
+		closed = i
+		if err := s.consumeIngredients(inventory, orders, &orders[i]); err != nil {
+			return err
+		}
+		// Orders placed before prices were captured are priced at close
+		if err := s.priceOrder(&orders[i], true); err != nil {
+			return err
+		}
+		if balance := balanceDue(orders[i]); balance > 0 {
+			return fmt.Errorf("%w of %.2f", ErrUnpaidBalance, balance)
+		}
+		setStatus(&orders[i], models.StatusClosed, time.Now())
 	}
-	if !checker {
+	if closed < 0 {
 		return errors.New("ID not found")
 	}
-
-	orderQuantity, menuItems, err := s.orderRepo.PresentInTheMenu(closeOrder)
+	rules, err := s.orderRepo.ReadLoyaltyRules()
 	if err != nil {
 		return err
 	}
-	invItems, err := s.orderRepo.PresentInTheInventory(orderQuantity, menuItems)
+	ledger, err := s.orderRepo.ReadLedger()
 	if err != nil {
 		return err
 	}
-	if err := s.orderRepo.WriteJSONEditIngredients(invItems); err != nil {
+	newLedger := earnRewards(ledger, orders[closed], rules, time.Now())
+
+	// Inventory, orders and the ledger are committed together so a failed write cannot deduct stock
+	// or credit points twice
+	if err := s.saveOrdersAndInventory(orders, inventory, changedLedger(ledger, newLedger)); err != nil {
 		return err
 	}
+	s.events.Publish(models.EventOrderClosed, orders[closed])
+	return nil
+}
+
+// Moves an order to a new status following the order lifecycle; moving to closed
+// goes through CloseOrder so the ingredients are deducted. Cancelling and refunding
+// need a reason and go through CancelOrder and RefundOrder.
+func (s *orderService) TransitionOrder(id string, status string) error {
+	switch {
+	case !isKnownStatus(status):
+		return fmt.Errorf("Unknown status: %s", status)
+	case status == models.StatusClosed:
+		return s.CloseOrder(id)
+	case status == models.StatusCancelled || status == models.StatusRefunded:
+		return errors.New("Orders are cancelled or refunded with a reason through their cancel or refund endpoint")
+	}
+	return s.changeOrder(id, models.EventStatusChanged, func(order *models.Order, inventory []models.InventoryItem, ledger *[]models.LoyaltyEntry) error {
+		if err := checkTransition(order.Status, status); err != nil {
+			return err
+		}
+		setStatus(order, status, time.Now())
+		return nil
+	})
+}
+
+// Cancels an active order, keeping it with the reason, releasing its reserved ingredients and
+// giving back the loyalty rewards it took
+func (s *orderService) CancelOrder(id string, reason string) error {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return errors.New("Missing reason")
+	}
+	return s.changeOrder(id, models.EventOrderCancelled, func(order *models.Order, inventory []models.InventoryItem, ledger *[]models.LoyaltyEntry) error {
+		if err := checkTransition(order.Status, models.StatusCancelled); err != nil {
+			return err
+		}
+		releaseIngredients(inventory, order)
+		*ledger = reverseOrderEntries(*ledger, order.ID, "Order cancelled", time.Now())
+		order.Reason = reason
+		setStatus(order, models.StatusCancelled, time.Now())
+		return nil
+	})
+}
 
-	for i, order := range orders {
-		if id == order.ID {
-			orders[i].Status = "closed"
+// Refunds a closed order, which pays back its payments, takes it out of the sales and undoes its
+// loyalty earnings and redemptions, optionally returning its ingredients to the inventory
+func (s *orderService) RefundOrder(id string, reason string, restock bool) error {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return errors.New("Missing reason")
+	}
+	return s.changeOrder(id, models.EventOrderRefunded, func(order *models.Order, inventory []models.InventoryItem, ledger *[]models.LoyaltyEntry) error {
+		if err := checkTransition(order.Status, models.StatusRefunded); err != nil {
+			return err
 		}
+		*ledger = reverseOrderEntries(*ledger, order.ID, "Order refunded", time.Now())
+		reversePayments(order, time.Now().Format(models.TimeLayout))
+		if restock {
+			if err := s.restockIngredients(inventory, order); err != nil {
+				return err
+			}
+		}
+		order.Reason = reason
+		setStatus(order, models.StatusRefunded, time.Now())
+		return nil
+	})
+}
+
+// Applies a change to one order, the inventory and the loyalty ledger under their locks, saves
+// them together and publishes an event of the given type. The menu is locked too, since
+// restocking an order closed before deductions were recorded reads its recipes.
+func (s *orderService) changeOrder(id string, eventType string, change func(order *models.Order, inventory []models.InventoryItem, ledger *[]models.LoyaltyEntry) error) error {
+	unlock := s.orderRepo.Lock(dal.InventoryitemFile, dal.MenuItemFile, dal.OrdersFile, dal.LoyaltyLedgerFile)
+	defer unlock()
+	orders, err := s.orderRepo.ReadJSONOrder()
+	if err != nil {
+		return err
 	}
-	err = s.orderRepo.WriteJSONNewOrder(orders)
+	inventory, err := s.orderRepo.ReadJSONInv()
 	if err != nil {
 		return err
 	}
-	return nil
+	ledger, err := s.orderRepo.ReadLedger()
+	if err != nil {
+		return err
+	}
+	for i := range orders {
+		if orders[i].ID != id {
+			continue
+		}
+		newLedger := ledger
+		if err := change(&orders[i], inventory, &newLedger); err != nil {
+			return err
+		}
+		if err := s.saveOrdersAndInventory(orders, inventory, changedLedger(ledger, newLedger)); err != nil {
+			return err
+		}
+		s.events.Publish(eventType, orders[i])
+		return nil
+	}
+	return errors.New("ID not found")
 }
 
-// Deletes an order by ID, returning an error if the ID is not found
+// Deletes an order by ID, releasing its reserved ingredients and undoing its loyalty entries,
+// returning an error if the ID is not found
 func (s *orderService) ServiceDeleteOrdersID(id string) error {
+	unlock := s.orderRepo.Lock(dal.InventoryitemFile, dal.MenuItemFile, dal.OrdersFile, dal.LoyaltyLedgerFile)
+	defer unlock()
 	orders, err := s.orderRepo.ReadJSONOrder()
 	if err != nil {
 		return err
 	}
+	inventory, err := s.orderRepo.ReadJSONInv()
+	if err != nil {
+		return err
+	}
 	index := 0
 	checker := false
 	for i, oneOrder := range orders {
@@ -213,53 +592,60 @@ func (s *orderService) ServiceDeleteOrdersID(id string) error {
 	if !checker {
 		return errors.New("Such ID not found")
 	}
+	// A deleted order no longer holds its ingredients
+	releaseIngredients(inventory, &orders[index])
+	ledger, err := s.orderRepo.ReadLedger()
+	if err != nil {
+		return err
+	}
+	newLedger := reverseOrderEntries(ledger, id, "Order deleted", time.Now())
+	deleted := orders[index]
 	orders = append(orders[:index], orders[index+1:]...)
-	if err := s.orderRepo.WriteJSONNewOrder(orders); err != nil {
+	if err := s.saveOrdersAndInventory(orders, inventory, changedLedger(ledger, newLedger)); err != nil {
 		return err
 	}
+	s.events.Publish(models.EventOrderDeleted, deleted)
 	return nil
 }
 
 // Retrieves all orders from the repository
 func (s *orderService) GetOrdersService() ([]models.Order, error) {
+	unlock := s.orderRepo.RLock(dal.OrdersFile)
+	defer unlock()
 	return s.orderRepo.ReadJSONOrder()
 }
 
 // Retrieves a specific order by ID, returning an error if the ID is not found
 func (s *orderService) GetIDOrdersService(id string) (models.Order, error) {
-	getOrder := models.Order{}
-	orders, err := s.orderRepo.ReadJSONOrder()
-	if err != nil {
-		return getOrder, err
-	}
-	checker := false
-	for _, oneOrder := range orders {
-		if id == oneOrder.ID {
-			checker = true
-			getOrder = oneOrder
-		}
-	}
-	if !checker {
+	unlock := s.orderRepo.RLock(dal.OrdersFile)
+	defer unlock()
+	getOrder, exists := s.orderRepo.FindOrder(id)
+	if !exists {
 		return getOrder, errors.New("ID not found")
 	}
 	return getOrder, nil
 }
 
-// Checks if all items in an order exist on the menu, returning an error if any item is missing
+// Checks if all items in an order exist on the menu and their variants and modifiers are valid choices,
+// returning an error if any item is missing
 func (s *orderService) IsItOnTheMenu(body models.Order) error {
 	menu, err := s.orderRepo.ReadJSONMenu()
 	if err != nil {
 		return err
 	}
-	menuItems := make(map[string]int)
+	menuItems := make(map[string]models.MenuItem)
 	for _, onemenuItem := range menu {
-		menuItems[onemenuItem.ID] = int(onemenuItem.Price)
+		menuItems[onemenuItem.ID] = onemenuItem
 	}
 	list := []string{}
 	for _, oneOrderItem := range body.Items {
-		_, exists := menuItems[oneOrderItem.ProductID]
+		menuItem, exists := menuItems[oneOrderItem.ProductID]
 		if !exists {
 			list = append(list, oneOrderItem.ProductID)
+			continue
+		}
+		if _, _, err := configureItem(menuItem, oneOrderItem); err != nil {
+			return err
 		}
 	}
 	if len(list) > 0 {
@@ -0,0 +1,202 @@
+package service
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"hot-coffee/internal/config"
+	"hot-coffee/internal/dal"
+	"hot-coffee/models"
+)
+
+// Stock the test inventory starts with
+const (
+	testShots = 100.0
+	testMilk  = 30000.0
+)
+
+// Returns the test menu's latte: one shot and 200 ml of milk
+func testLatte() models.MenuItem {
+	return models.MenuItem{
+		ID: "latte", Name: "Latte", Description: "Espresso with steamed milk", Price: 3.5,
+		Ingredients: []models.MenuItemIngredient{{IngredientID: "espresso_shot", Quantity: 1}, {IngredientID: "milk", Quantity: 200}},
+	}
+}
+
+// Writes a value as a JSON data file
+func writeTestJSON(t *testing.T, path string, value any) {
+	t.Helper()
+	data, err := json.Marshal(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Returns a store over a fresh data directory holding the latte on the menu and its ingredients
+func newTestStore(t *testing.T) (*dal.Store, config.Config) {
+	t.Helper()
+	dir := t.TempDir()
+	cfg := config.Default()
+	cfg.DataDir, cfg.SeedDir, cfg.BackupDir = dir, dir, filepath.Join(dir, "backups")
+	writeTestJSON(t, cfg.MenuPath(), []models.MenuItem{testLatte()})
+	writeTestJSON(t, cfg.InventoryPath(), []models.InventoryItem{
+		{IngredientID: "espresso_shot", Name: "Espresso Shot", Quantity: testShots, Unit: "shots"},
+		{IngredientID: "milk", Name: "Milk", Quantity: testMilk, Unit: "ml"},
+	})
+	if err := dal.Bootstrap(cfg); err != nil {
+		t.Fatal(err)
+	}
+	store, err := dal.LoadStore(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store, cfg
+}
+
+// Returns an order service over a fresh store, with the sequence ID strategy
+func newTestOrderService(t *testing.T) (OrderService, *dal.Store) {
+	t.Helper()
+	store, cfg := newTestStore(t)
+	orderRepo := dal.NewJSONOrderRepository(store, dal.NewFileLocks())
+	idGen, err := NewIDGenerator(IDStrategySequence, dal.NewJSONSequenceRepository(cfg), orderRepo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewOrderService(orderRepo, idGen, 0, time.Duration(cfg.PickupLeadTime), NewEventBus()), store
+}
+
+// Returns a request for the given number of lattes
+func latteRequest(customer string, quantity int) models.OrderRequest {
+	return models.OrderRequest{CustomerName: customer, Items: []models.OrderItem{{ProductID: "latte", Quantity: quantity}}}
+}
+
+// Rejected orders do not use up an ID, so the accepted ones are numbered without gaps
+func TestPostOrdersRejectedTakesNoID(t *testing.T) {
+	orders, store := newTestOrderService(t)
+	rejected := []models.OrderRequest{
+		latteRequest("", 1),
+		{CustomerName: "Ada", Items: []models.OrderItem{{ProductID: "mocha", Quantity: 1}}},
+		latteRequest("Ada", int(testShots)+1),
+		{CustomerName: "Ada", Items: []models.OrderItem{{ProductID: "latte", Quantity: 1}}, PickupAt: "yesterday"},
+	}
+	for i, request := range rejected {
+		if _, err := orders.ServicePostOrders(request); err == nil {
+			t.Fatalf("request %d was accepted", i)
+		}
+		order, err := orders.ServicePostOrders(latteRequest("Ada", 1))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := strconv.Itoa(i + 1); order.ID != want {
+			t.Errorf("order ID is %s, want %s", order.ID, want)
+		}
+		if _, exists := store.Order(order.ID); !exists {
+			t.Errorf("returned order %s is not saved", order.ID)
+		}
+	}
+}
+
+// A quote prices the order and reports the shortfalls against the stock not reserved yet,
+// without saving or reserving anything
+func TestQuoteOrder(t *testing.T) {
+	orders, store := newTestOrderService(t)
+	if _, err := orders.ServicePostOrders(latteRequest("Ada", 98)); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name       string
+		quantity   int
+		total      float64
+		shortfalls []models.Shortfall
+	}{
+		{"in stock", 2, 7, []models.Shortfall{}},
+		{"short of shots", 3, 10.5, []models.Shortfall{{IngredientID: "espresso_shot", Required: 3, Available: 2, Missing: 1}}},
+		{"short of both", 60, 210, []models.Shortfall{
+			{IngredientID: "espresso_shot", Required: 60, Available: 2, Missing: 58},
+			{IngredientID: "milk", Required: 12000, Available: 10400, Missing: 1600},
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			quote, err := orders.QuoteOrder(latteRequest("Bob", test.quantity))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if quote.Subtotal != test.total || quote.Total != test.total || quote.Items[0].UnitPrice != 3.5 {
+				t.Errorf("quote is %+v, want a total of %.2f", quote, test.total)
+			}
+			if quote.Available != (len(test.shortfalls) == 0) || !slices.Equal(quote.Shortfalls, test.shortfalls) {
+				t.Errorf("quote reports available %v and %+v, want %+v", quote.Available, quote.Shortfalls, test.shortfalls)
+			}
+		})
+	}
+	if got := len(store.Orders()); got != 1 {
+		t.Errorf("%d orders after quoting, want 1", got)
+	}
+	for _, item := range store.Inventory() {
+		if want := map[string]float64{"espresso_shot": 98, "milk": 19600}[item.IngredientID]; item.Reserved != want {
+			t.Errorf("%s reserves %v after quoting, want %v", item.IngredientID, item.Reserved, want)
+		}
+	}
+}
+
+// A quote validates the order as placing it would
+func TestQuoteOrderInvalid(t *testing.T) {
+	orders, _ := newTestOrderService(t)
+	invalid := []models.OrderRequest{
+		{Items: []models.OrderItem{{ProductID: "latte", Quantity: 1}}},
+		{CustomerName: "Ada", Items: []models.OrderItem{{ProductID: "mocha", Quantity: 1}}},
+		{CustomerName: "Ada", Items: []models.OrderItem{{ProductID: "latte", Quantity: 0}}},
+		{CustomerName: "Ada", CustomerID: "missing", Items: []models.OrderItem{{ProductID: "latte", Quantity: 1}}},
+	}
+	for i, body := range invalid {
+		if _, err := orders.QuoteOrder(body); err == nil {
+			t.Errorf("order %d was quoted", i)
+		}
+	}
+}
+
+// Orders are checked against the stock left by the orders due before them: a pre-order does
+// not block orders due earlier, but takes the stock of every order due by its pickup
+func TestPostOrdersChecksStockAtPickup(t *testing.T) {
+	orders, _ := newTestOrderService(t)
+	pickup := func(days int) string {
+		return time.Now().AddDate(0, 0, days).Format(models.TimeLayout)
+	}
+	steps := []struct {
+		name     string
+		quantity int
+		pickupAt string
+		wantErr  bool
+	}{
+		{"pre-order holding every shot", int(testShots), pickup(2), false},
+		{"order for now", 1, "", false},
+		{"pre-order due after both", 1, pickup(3), true},
+		{"pre-order due before the first", int(testShots) - 1, pickup(1), false},
+		{"pre-order due before the first, beyond the rest", 1, pickup(1), true},
+	}
+	for _, step := range steps {
+		request := latteRequest("Ada", step.quantity)
+		request.PickupAt = step.pickupAt
+		if _, err := orders.ServicePostOrders(request); (err != nil) != step.wantErr {
+			t.Fatalf("%s: error %v, want error %v", step.name, err, step.wantErr)
+		}
+	}
+	request := latteRequest("Bob", 1)
+	request.PickupAt = pickup(3)
+	quote, err := orders.QuoteOrder(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if quote.Available {
+		t.Errorf("quote after every pre-order is available: %+v", quote)
+	}
+}
@@ -0,0 +1,54 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"hot-coffee/models"
+)
+
+// ErrIllegalTransition is returned when an order cannot move from its status to the requested one
+var ErrIllegalTransition = errors.New("Illegal status transition")
+
+// Lifecycle of an order: each status maps to the statuses the order may move to next.
+// Pre-orders wait as scheduled until shortly before their pickup time.
+// Closing deducts the ingredients from the inventory and is allowed from any active
+// status; a closed order can only be refunded, and cancelled and refunded orders are final.
+var orderTransitions = map[string][]string{
+	models.StatusScheduled:     {models.StatusOpen, models.StatusCancelled, models.StatusClosed},
+	models.StatusOpen:          {models.StatusAccepted, models.StatusCancelled, models.StatusClosed},
+	models.StatusAccepted:      {models.StatusInPreparation, models.StatusCancelled, models.StatusClosed},
+	models.StatusInPreparation: {models.StatusReady, models.StatusCancelled, models.StatusClosed},
+	models.StatusReady:         {models.StatusPickedUp, models.StatusClosed},
+	models.StatusPickedUp:      {models.StatusClosed},
+	models.StatusClosed:        {models.StatusRefunded},
+	models.StatusCancelled:     {},
+	models.StatusRefunded:      {},
+}
+
+// Reports whether status is one of the known order statuses
+func isKnownStatus(status string) bool {
+	_, exists := orderTransitions[status]
+	return exists
+}
+
+// Reports whether an order in this status is still being worked on
+func isActiveStatus(status string) bool {
+	return status != models.StatusClosed && status != models.StatusCancelled && status != models.StatusRefunded
+}
+
+// Returns an ErrIllegalTransition error unless an order may move from one status to the other
+func checkTransition(from, to string) error {
+	if !slices.Contains(orderTransitions[from], to) {
+		return fmt.Errorf("%w: order is %s and cannot become %s", ErrIllegalTransition, from, to)
+	}
+	return nil
+}
+
+// Sets the status of an order and records when it changed
+func setStatus(order *models.Order, status string, at time.Time) {
+	order.Status = status
+	order.StatusHistory = append(order.StatusHistory, models.StatusChange{Status: status, At: at.Format(models.TimeLayout)})
+}
@@ -0,0 +1,118 @@
+package service
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"hot-coffee/models"
+)
+
+// Every pair of statuses is checked against the lifecycle: only the listed moves are legal
+func TestCheckTransition(t *testing.T) {
+	allowed := map[string][]string{
+		models.StatusScheduled:     {models.StatusOpen, models.StatusCancelled, models.StatusClosed},
+		models.StatusOpen:          {models.StatusAccepted, models.StatusCancelled, models.StatusClosed},
+		models.StatusAccepted:      {models.StatusInPreparation, models.StatusCancelled, models.StatusClosed},
+		models.StatusInPreparation: {models.StatusReady, models.StatusCancelled, models.StatusClosed},
+		models.StatusReady:         {models.StatusPickedUp, models.StatusClosed},
+		models.StatusPickedUp:      {models.StatusClosed},
+		models.StatusClosed:        {models.StatusRefunded},
+		models.StatusCancelled:     nil,
+		models.StatusRefunded:      nil,
+	}
+	statuses := []string{
+		models.StatusScheduled, models.StatusOpen, models.StatusAccepted, models.StatusInPreparation, models.StatusReady,
+		models.StatusPickedUp, models.StatusClosed, models.StatusCancelled, models.StatusRefunded, "unknown",
+	}
+	for _, from := range statuses {
+		for _, to := range statuses {
+			legal := false
+			for _, next := range allowed[from] {
+				legal = legal || next == to
+			}
+			err := checkTransition(from, to)
+			if legal && err != nil {
+				t.Errorf("%s -> %s: %v, want legal", from, to, err)
+			}
+			if !legal && !errors.Is(err, ErrIllegalTransition) {
+				t.Errorf("%s -> %s: %v, want ErrIllegalTransition", from, to, err)
+			}
+		}
+	}
+}
+
+func TestStatusKinds(t *testing.T) {
+	tests := []struct {
+		status        string
+		known, active bool
+	}{
+		{models.StatusScheduled, true, true},
+		{models.StatusOpen, true, true},
+		{models.StatusReady, true, true},
+		{models.StatusPickedUp, true, true},
+		{models.StatusClosed, true, false},
+		{models.StatusCancelled, true, false},
+		{models.StatusRefunded, true, false},
+	}
+	for _, test := range tests {
+		if got := isKnownStatus(test.status); got != test.known {
+			t.Errorf("isKnownStatus(%s) = %v, want %v", test.status, got, test.known)
+		}
+		if got := isActiveStatus(test.status); got != test.active {
+			t.Errorf("isActiveStatus(%s) = %v, want %v", test.status, got, test.active)
+		}
+	}
+}
+
+// An order walks the lifecycle through TransitionOrder, which rejects skipped steps and the
+// statuses that have their own endpoints, and records each change in the history
+func TestTransitionOrder(t *testing.T) {
+	orders, store := newTestOrderService(t)
+	if _, err := orders.ServicePostOrders(latteRequest("Ada", 1)); err != nil {
+		t.Fatal(err)
+	}
+	steps := []struct {
+		status  string
+		wantErr bool
+	}{
+		{models.StatusReady, true},
+		{models.StatusAccepted, false},
+		{models.StatusOpen, true},
+		{models.StatusInPreparation, false},
+		{models.StatusCancelled, true},
+		{models.StatusRefunded, true},
+		{"lost", true},
+		{models.StatusReady, false},
+		{models.StatusPickedUp, false},
+		{models.StatusClosed, true}, // Unpaid.
+	}
+	for _, step := range steps {
+		err := orders.TransitionOrder("1", step.status)
+		if (err != nil) != step.wantErr {
+			t.Fatalf("transition to %s: %v, want error %v", step.status, err, step.wantErr)
+		}
+	}
+	order, _ := store.Order("1")
+	if order.Status != models.StatusPickedUp {
+		t.Errorf("order is %s, want %s", order.Status, models.StatusPickedUp)
+	}
+	var history []string
+	for _, change := range order.StatusHistory {
+		history = append(history, change.Status)
+	}
+	want := []string{models.StatusOpen, models.StatusAccepted, models.StatusInPreparation, models.StatusReady, models.StatusPickedUp}
+	if !slices.Equal(history, want) {
+		t.Errorf("history is %v, want %v", history, want)
+	}
+
+	if _, err := orders.PayOrder("1", []models.Payment{{Method: models.TenderCard, Amount: order.Total}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := orders.TransitionOrder("1", models.StatusClosed); err != nil {
+		t.Fatal(err)
+	}
+	if err := orders.TransitionOrder("1", models.StatusOpen); !errors.Is(err, ErrIllegalTransition) {
+		t.Errorf("reopening a closed order: %v, want ErrIllegalTransition", err)
+	}
+}
@@ -0,0 +1,146 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"hot-coffee/internal/dal"
+	"hot-coffee/models"
+)
+
+// ErrPromotionNotFound is returned for an ID that does not name an existing promotion
+var ErrPromotionNotFound = errors.New("Promotion not found")
+
+// PromotionService manages the promotions applied when orders are priced
+type PromotionService interface {
+	ListPromotions() ([]models.Promotion, error)
+	GetPromotion(id string) (models.Promotion, error)
+	CreatePromotion(promotion models.Promotion) error
+	UpdatePromotion(id string, promotion models.Promotion) error
+	DeletePromotion(id string) error
+}
+
+type promotionService struct {
+	promotionRepo dal.PromotionRepository
+}
+
+// Initializes and returns a new instance of promotionService with the provided repository
+func NewPromotionService(promotionRepo dal.PromotionRepository) PromotionService {
+	return &promotionService{promotionRepo: promotionRepo}
+}
+
+// Returns all promotions in the order they apply
+func (s *promotionService) ListPromotions() ([]models.Promotion, error) {
+	unlock := s.promotionRepo.RLock(dal.PromotionsFile)
+	defer unlock()
+	return s.promotionRepo.ReadPromotions()
+}
+
+// Returns the promotion with the given ID
+func (s *promotionService) GetPromotion(id string) (models.Promotion, error) {
+	unlock := s.promotionRepo.RLock(dal.PromotionsFile)
+	defer unlock()
+	promotion, exists := s.promotionRepo.FindPromotion(id)
+	if !exists {
+		return models.Promotion{}, ErrPromotionNotFound
+	}
+	return promotion, nil
+}
+
+// Adds a promotion under a new ID; it applies after the existing ones
+func (s *promotionService) CreatePromotion(promotion models.Promotion) error {
+	unlock := s.promotionRepo.Lock(dal.PromotionsFile, dal.MenuItemFile)
+	defer unlock()
+	promotions, err := s.promotionRepo.ReadPromotions()
+	if err != nil {
+		return err
+	}
+	if err := s.checkPromotion(&promotion, promotions); err != nil {
+		return err
+	}
+	if _, exists := s.promotionRepo.FindPromotion(promotion.ID); exists {
+		return errors.New("Such ID already exists")
+	}
+	return s.promotionRepo.WritePromotions(append(promotions, promotion))
+}
+
+// Replaces a promotion, keeping its place in the order promotions apply; orders already placed keep their discounts
+func (s *promotionService) UpdatePromotion(id string, promotion models.Promotion) error {
+	unlock := s.promotionRepo.Lock(dal.PromotionsFile, dal.MenuItemFile)
+	defer unlock()
+	promotions, err := s.promotionRepo.ReadPromotions()
+	if err != nil {
+		return err
+	}
+	i := slices.IndexFunc(promotions, func(p models.Promotion) bool { return p.ID == id })
+	if i < 0 {
+		return ErrPromotionNotFound
+	}
+	promotion.ID = id
+	if err := s.checkPromotion(&promotion, slices.Delete(slices.Clone(promotions), i, i+1)); err != nil {
+		return err
+	}
+	promotions[i] = promotion
+	return s.promotionRepo.WritePromotions(promotions)
+}
+
+// Deletes a promotion; orders already placed keep their discounts
+func (s *promotionService) DeletePromotion(id string) error {
+	unlock := s.promotionRepo.Lock(dal.PromotionsFile)
+	defer unlock()
+	promotions, err := s.promotionRepo.ReadPromotions()
+	if err != nil {
+		return err
+	}
+	i := slices.IndexFunc(promotions, func(p models.Promotion) bool { return p.ID == id })
+	if i < 0 {
+		return ErrPromotionNotFound
+	}
+	return s.promotionRepo.WritePromotions(slices.Delete(promotions, i, i+1))
+}
+
+// Validates a promotion against the other promotions and normalizes its code and days; every
+// product it names must be on the menu and its code must not be taken
+func (s *promotionService) checkPromotion(promotion *models.Promotion, others []models.Promotion) error {
+	if strings.TrimSpace(promotion.ID) == "" {
+		return errors.New("Missing promotion id")
+	}
+	if strings.TrimSpace(promotion.Name) == "" {
+		return errors.New("Missing promotion name")
+	}
+	if err := dal.CheckPromotionRules(*promotion); err != nil {
+		return err
+	}
+	promotion.Code = normalizePromoCode(promotion.Code)
+	if promotion.Code != "" && slices.ContainsFunc(others, func(p models.Promotion) bool { return normalizePromoCode(p.Code) == promotion.Code }) {
+		return fmt.Errorf("Promo code already in use: %s", promotion.Code)
+	}
+	for i, day := range promotion.Days {
+		promotion.Days[i] = strings.ToLower(strings.TrimSpace(day))
+		if !slices.Contains(promoDays, promotion.Days[i]) {
+			return fmt.Errorf("Unknown day %q, expected one of %s", day, strings.Join(promoDays, ", "))
+		}
+	}
+	for _, clock := range []string{promotion.StartTime, promotion.EndTime} {
+		if _, err := time.Parse(models.PromoTimeLayout, clock); clock != "" && err != nil {
+			return fmt.Errorf("Invalid time of day %q, expected HH:MM", clock)
+		}
+	}
+	for _, date := range []string{promotion.ValidFrom, promotion.ValidTo} {
+		if _, err := time.Parse(models.PromoDateLayout, date); date != "" && err != nil {
+			return fmt.Errorf("Invalid date %q, expected YYYY-MM-DD", date)
+		}
+	}
+	if promotion.ValidFrom != "" && promotion.ValidTo != "" && promotion.ValidTo < promotion.ValidFrom {
+		return errors.New("Valid to cannot be before valid from")
+	}
+	for _, productID := range slices.Concat(promotion.ProductIDs, promotion.RequiresProductIDs) {
+		if _, exists := s.promotionRepo.FindMenuItem(productID); !exists {
+			return fmt.Errorf("This item is not on the menu: %s", productID)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,198 @@
+package service
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"hot-coffee/models"
+)
+
+// Weekday names accepted in a promotion's days
+var promoDays = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+// One unit of an order line a promotion can discount
+type promoUnit struct {
+	line  int
+	value float64
+}
+
+// Applies the promotions to an order whose line totals are computed: every item's Discount is
+// set to what the promotions take off it and the order records the promotions that applied.
+// Promotions apply in the order they are stored, each on what the earlier ones left of a line,
+// so a line is never discounted below zero. Reward lines are already free and are left out.
+func applyPromotions(order *models.Order, promotions []models.Promotion, now time.Time) error {
+	codes, err := checkPromoCodes(order.PromoCodes, promotions, now)
+	if err != nil {
+		return err
+	}
+	order.PromoCodes = codes
+	order.Discounts = nil
+	remaining := make([]float64, len(order.Items))
+	for i, item := range order.Items {
+		order.Items[i].Discount = 0
+		if item.Reward == "" {
+			remaining[i] = item.LineTotal
+		}
+	}
+	for _, promotion := range promotions {
+		if promotion.Code != "" && !slices.Contains(codes, normalizePromoCode(promotion.Code)) {
+			continue
+		}
+		if !promotionRunning(promotion, now) {
+			continue
+		}
+		discounts := promotionDiscounts(promotion, order.Items, remaining)
+		applied := 0.0
+		for i, discount := range discounts {
+			discount = roundMoney(min(discount, remaining[i]))
+			if discount <= 0 {
+				continue
+			}
+			remaining[i] = roundMoney(remaining[i] - discount)
+			order.Items[i].Discount = roundMoney(order.Items[i].Discount + discount)
+			applied += discount
+		}
+		if applied > 0 {
+			order.Discounts = append(order.Discounts, models.AppliedDiscount{
+				PromotionID: promotion.ID,
+				Name:        promotion.Name,
+				Amount:      roundMoney(applied),
+			})
+		}
+	}
+	return nil
+}
+
+// Normalizes the promo codes on an order and checks each names a promotion running now
+func checkPromoCodes(codes []string, promotions []models.Promotion, now time.Time) ([]string, error) {
+	var result []string
+	for _, code := range codes {
+		code = normalizePromoCode(code)
+		if code == "" || slices.Contains(result, code) {
+			continue
+		}
+		i := slices.IndexFunc(promotions, func(p models.Promotion) bool { return normalizePromoCode(p.Code) == code })
+		if i < 0 {
+			return nil, fmt.Errorf("Unknown promo code: %s", code)
+		}
+		if !promotionRunning(promotions[i], now) {
+			return nil, fmt.Errorf("Promo code %s is not valid now", code)
+		}
+		result = append(result, code)
+	}
+	return result, nil
+}
+
+// Promo codes are matched regardless of case and surrounding spaces
+func normalizePromoCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// Reports whether the promotion runs at the given time: on one of its days, within its time
+// of day and between its first and last day
+func promotionRunning(promotion models.Promotion, now time.Time) bool {
+	today := now.Format(models.PromoDateLayout)
+	if promotion.ValidFrom != "" && today < promotion.ValidFrom {
+		return false
+	}
+	if promotion.ValidTo != "" && today > promotion.ValidTo {
+		return false
+	}
+	if len(promotion.Days) > 0 && !slices.Contains(promotion.Days, promoDays[now.Weekday()]) {
+		return false
+	}
+	if promotion.StartTime == "" && promotion.EndTime == "" {
+		return true
+	}
+	// Times of day in PromoTimeLayout compare correctly as strings
+	clock := now.Format(models.PromoTimeLayout)
+	start, end := promotion.StartTime, promotion.EndTime
+	if end == "" {
+		end = "24:00"
+	}
+	if end <= start {
+		return clock >= start || clock < end
+	}
+	return clock >= start && clock < end
+}
+
+// Returns how much the promotion takes off each line, given what is left of the lines
+func promotionDiscounts(promotion models.Promotion, items []models.OrderItem, remaining []float64) []float64 {
+	discounts := make([]float64, len(items))
+	required := 0
+	for _, item := range items {
+		if item.Reward == "" && slices.Contains(promotion.RequiresProductIDs, item.ProductID) {
+			required += item.Quantity
+		}
+	}
+	if len(promotion.RequiresProductIDs) > 0 && required == 0 {
+		return discounts
+	}
+	var units []promoUnit
+	for i, item := range items {
+		if item.Reward != "" || remaining[i] <= 0 {
+			continue
+		}
+		if len(promotion.ProductIDs) > 0 && !slices.Contains(promotion.ProductIDs, item.ProductID) {
+			continue
+		}
+		for range item.Quantity {
+			units = append(units, promoUnit{line: i, value: remaining[i] / float64(item.Quantity)})
+		}
+	}
+	// The cheapest units are discounted first
+	slices.SortStableFunc(units, func(a, b promoUnit) int { return cmp.Compare(a.value, b.value) })
+
+	switch promotion.Type {
+	case models.PromoBuyXGetY:
+		if promotion.BuyQuantity < 1 || promotion.GetQuantity < 1 {
+			// Such a promotion is rejected when saved or loaded; one that slipped through gives nothing
+			return discounts
+		}
+		free := len(units) / (promotion.BuyQuantity + promotion.GetQuantity) * promotion.GetQuantity
+		for _, unit := range units[:free] {
+			discounts[unit.line] += unit.value
+		}
+		return discounts
+	case models.PromoFixedOff:
+		if len(promotion.ProductIDs) == 0 {
+			// An amount off the whole order is spread over the lines in proportion to what is left of them
+			total := 0.0
+			for _, unit := range units {
+				total += unit.value
+			}
+			if total <= 0 {
+				return discounts
+			}
+			share := min(promotion.Amount, total) / total
+			for _, unit := range units {
+				discounts[unit.line] += unit.value * share
+			}
+			return discounts
+		}
+	}
+	// A combo discounts one unit per required item bought
+	if len(promotion.RequiresProductIDs) > 0 && len(units) > required {
+		units = units[:required]
+	}
+	for _, unit := range units {
+		discounts[unit.line] += unitDiscount(promotion, unit.value)
+	}
+	return discounts
+}
+
+// Returns how much a per-unit promotion takes off one unit worth value
+func unitDiscount(promotion models.Promotion, value float64) float64 {
+	switch promotion.Type {
+	case models.PromoPercentOff:
+		return value * promotion.Percent / 100
+	case models.PromoFixedOff:
+		return min(promotion.Amount, value)
+	case models.PromoFixedPrice:
+		return max(value-promotion.Price, 0)
+	}
+	return 0
+}
@@ -0,0 +1,226 @@
+package service
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"hot-coffee/models"
+)
+
+// Returns an order line of quantity units at unitPrice with its line total computed
+func promoLine(productID string, quantity int, unitPrice float64) models.OrderItem {
+	return models.OrderItem{ProductID: productID, Quantity: quantity, UnitPrice: unitPrice, LineTotal: roundMoney(unitPrice * float64(quantity))}
+}
+
+func TestPromotionDiscounts(t *testing.T) {
+	reward := promoLine("latte", 1, 0)
+	reward.Reward = "card"
+	tests := []struct {
+		name      string
+		promotion models.Promotion
+		items     []models.OrderItem
+		remaining []float64 // nil means the line totals.
+		want      []float64
+	}{
+		{
+			name:      "buy two get one free",
+			promotion: models.Promotion{Type: models.PromoBuyXGetY, ProductIDs: []string{"latte"}, BuyQuantity: 2, GetQuantity: 1},
+			items:     []models.OrderItem{promoLine("latte", 5, 3.5), promoLine("muffin", 1, 2)},
+			want:      []float64{3.5, 0},
+		},
+		{
+			name:      "buy one get one takes the cheapest unit",
+			promotion: models.Promotion{Type: models.PromoBuyXGetY, BuyQuantity: 1, GetQuantity: 1},
+			items:     []models.OrderItem{promoLine("latte", 1, 3.5), promoLine("cookie", 1, 1)},
+			want:      []float64{0, 1},
+		},
+		{
+			name:      "buy x get y without quantities gives nothing",
+			promotion: models.Promotion{Type: models.PromoBuyXGetY},
+			items:     []models.OrderItem{promoLine("latte", 4, 3.5)},
+			want:      []float64{0},
+		},
+		{
+			name:      "fixed off the order spread in proportion",
+			promotion: models.Promotion{Type: models.PromoFixedOff, Amount: 2},
+			items:     []models.OrderItem{promoLine("latte", 2, 3.5), promoLine("cookie", 1, 1)},
+			want:      []float64{1.75, 0.25},
+		},
+		{
+			name:      "fixed off the order spread over what is left",
+			promotion: models.Promotion{Type: models.PromoFixedOff, Amount: 2},
+			items:     []models.OrderItem{promoLine("latte", 2, 3.5), promoLine("cookie", 1, 1)},
+			remaining: []float64{3, 1},
+			want:      []float64{1.5, 0.5},
+		},
+		{
+			name:      "fixed off more than the order",
+			promotion: models.Promotion{Type: models.PromoFixedOff, Amount: 20},
+			items:     []models.OrderItem{promoLine("latte", 2, 3.5), promoLine("cookie", 1, 1)},
+			want:      []float64{7, 1},
+		},
+		{
+			name:      "fixed off each named product",
+			promotion: models.Promotion{Type: models.PromoFixedOff, Amount: 1, ProductIDs: []string{"latte", "cookie"}},
+			items:     []models.OrderItem{promoLine("latte", 2, 3.5), promoLine("cookie", 1, 0.5), promoLine("muffin", 1, 2)},
+			want:      []float64{2, 0.5, 0},
+		},
+		{
+			name:      "percent off",
+			promotion: models.Promotion{Type: models.PromoPercentOff, Percent: 10},
+			items:     []models.OrderItem{promoLine("latte", 2, 3.5), promoLine("cookie", 1, 1)},
+			want:      []float64{0.7, 0.1},
+		},
+		{
+			name:      "fixed price",
+			promotion: models.Promotion{Type: models.PromoFixedPrice, Price: 3, ProductIDs: []string{"latte", "cookie"}},
+			items:     []models.OrderItem{promoLine("latte", 2, 3.5), promoLine("cookie", 1, 1)},
+			want:      []float64{1, 0},
+		},
+		{
+			name:      "combo discounts one unit per required item",
+			promotion: models.Promotion{Type: models.PromoFixedPrice, Price: 1, ProductIDs: []string{"muffin"}, RequiresProductIDs: []string{"latte"}},
+			items:     []models.OrderItem{promoLine("latte", 1, 3.5), promoLine("muffin", 3, 2)},
+			want:      []float64{0, 1},
+		},
+		{
+			name:      "combo without the required product",
+			promotion: models.Promotion{Type: models.PromoFixedPrice, Price: 1, ProductIDs: []string{"muffin"}, RequiresProductIDs: []string{"latte"}},
+			items:     []models.OrderItem{promoLine("muffin", 3, 2)},
+			want:      []float64{0},
+		},
+		{
+			name:      "reward lines neither qualify nor count as required",
+			promotion: models.Promotion{Type: models.PromoPercentOff, Percent: 50, RequiresProductIDs: []string{"latte"}},
+			items:     []models.OrderItem{reward, promoLine("muffin", 1, 2)},
+			want:      []float64{0, 0},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			remaining := tt.remaining
+			if remaining == nil {
+				for _, item := range tt.items {
+					remaining = append(remaining, item.LineTotal)
+				}
+			}
+			got := promotionDiscounts(tt.promotion, tt.items, remaining)
+			for i := range got {
+				got[i] = roundMoney(got[i])
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("promotionDiscounts() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPromotionRunning(t *testing.T) {
+	// A Saturday
+	at := func(clock string) time.Time {
+		parsed, err := time.Parse("2006-01-02 15:04", "2026-10-17 "+clock)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return parsed
+	}
+	lateNight := models.Promotion{StartTime: "22:00", EndTime: "02:00"}
+	tests := []struct {
+		name      string
+		promotion models.Promotion
+		now       time.Time
+		want      bool
+	}{
+		{name: "always", promotion: models.Promotion{}, now: at("12:00"), want: true},
+		{name: "inside the hours", promotion: models.Promotion{StartTime: "15:00", EndTime: "17:00"}, now: at("15:00"), want: true},
+		{name: "at the end of the hours", promotion: models.Promotion{StartTime: "15:00", EndTime: "17:00"}, now: at("17:00"), want: false},
+		{name: "open-ended hours", promotion: models.Promotion{StartTime: "15:00"}, now: at("23:59"), want: true},
+		{name: "past midnight before it", promotion: lateNight, now: at("23:30"), want: true},
+		{name: "past midnight after it", promotion: lateNight, now: at("01:59"), want: true},
+		{name: "past midnight at the end", promotion: lateNight, now: at("02:00"), want: false},
+		{name: "past midnight during the day", promotion: lateNight, now: at("12:00"), want: false},
+		{name: "on one of its days", promotion: models.Promotion{Days: []string{"sat", "sun"}}, now: at("12:00"), want: true},
+		{name: "not on its days", promotion: models.Promotion{Days: []string{"mon"}}, now: at("12:00"), want: false},
+		{name: "on its last day", promotion: models.Promotion{ValidFrom: "2026-10-01", ValidTo: "2026-10-17"}, now: at("23:59"), want: true},
+		{name: "before its first day", promotion: models.Promotion{ValidFrom: "2026-10-18"}, now: at("12:00"), want: false},
+		{name: "after its last day", promotion: models.Promotion{ValidTo: "2026-10-16"}, now: at("12:00"), want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := promotionRunning(tt.promotion, tt.now); got != tt.want {
+				t.Errorf("promotionRunning() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyPromotions(t *testing.T) {
+	now := time.Date(2026, 10, 17, 23, 30, 0, 0, time.UTC)
+	half := models.Promotion{ID: "half", Name: "Half price", Type: models.PromoPercentOff, Percent: 50}
+	fiveOff := models.Promotion{ID: "five", Name: "Five off", Type: models.PromoFixedOff, Amount: 5}
+	coded := models.Promotion{ID: "code", Name: "Code", Type: models.PromoFixedOff, Amount: 1, Code: "Save1"}
+	expired := models.Promotion{ID: "old", Name: "Old", Type: models.PromoFixedOff, Amount: 1, Code: "OLD", ValidTo: "2026-01-01"}
+	lateNight := models.Promotion{ID: "late", Name: "Late night", Type: models.PromoFixedPrice, Price: 2, StartTime: "22:00", EndTime: "02:00"}
+	tests := []struct {
+		name          string
+		promotions    []models.Promotion
+		codes         []string
+		wantCodes     []string
+		wantDiscount  []float64 // Discount of each line.
+		wantDiscounts []models.AppliedDiscount
+		wantErr       bool
+	}{
+		{
+			name:          "later promotions apply to what the earlier ones left",
+			promotions:    []models.Promotion{half, fiveOff},
+			wantDiscount:  []float64{7},
+			wantDiscounts: []models.AppliedDiscount{{PromotionID: "half", Name: "Half price", Amount: 3.5}, {PromotionID: "five", Name: "Five off", Amount: 3.5}},
+		},
+		{
+			name:          "a promotion with a code needs the code",
+			promotions:    []models.Promotion{coded},
+			wantDiscount:  []float64{0},
+			wantDiscounts: nil,
+		},
+		{
+			name:          "codes match regardless of case and spaces",
+			promotions:    []models.Promotion{coded},
+			codes:         []string{" save1 ", "SAVE1"},
+			wantCodes:     []string{"SAVE1"},
+			wantDiscount:  []float64{1},
+			wantDiscounts: []models.AppliedDiscount{{PromotionID: "code", Name: "Code", Amount: 1}},
+		},
+		{
+			name:          "a window past midnight applies late at night",
+			promotions:    []models.Promotion{lateNight},
+			wantDiscount:  []float64{3},
+			wantDiscounts: []models.AppliedDiscount{{PromotionID: "late", Name: "Late night", Amount: 3}},
+		},
+		{name: "unknown code", promotions: []models.Promotion{coded}, codes: []string{"NOPE"}, wantErr: true},
+		{name: "code no longer valid", promotions: []models.Promotion{expired}, codes: []string{"old"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			order := models.Order{Items: []models.OrderItem{promoLine("latte", 2, 3.5)}, PromoCodes: tt.codes}
+			err := applyPromotions(&order, tt.promotions, now)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("applyPromotions() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !slices.Equal(order.PromoCodes, tt.wantCodes) {
+				t.Errorf("promo codes are %v, want %v", order.PromoCodes, tt.wantCodes)
+			}
+			for i, item := range order.Items {
+				if item.Discount != tt.wantDiscount[i] {
+					t.Errorf("line %d has discount %.2f, want %.2f", i, item.Discount, tt.wantDiscount[i])
+				}
+			}
+			if !slices.Equal(order.Discounts, tt.wantDiscounts) {
+				t.Errorf("discounts are %+v, want %+v", order.Discounts, tt.wantDiscounts)
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"hot-coffee/internal/dal"
+	"hot-coffee/models"
+)
+
+// ErrTaxRateNotFound is returned for an ID that does not name an existing tax rate
+var ErrTaxRateNotFound = errors.New("Tax rate not found")
+
+// TaxService manages the tax rates applied when orders are priced
+type TaxService interface {
+	ListRates() ([]models.TaxRate, error)
+	CreateRate(rate models.TaxRate) error
+	UpdateRate(id string, rate models.TaxRate) error
+	DeleteRate(id string) error
+}
+
+type taxService struct {
+	taxRepo dal.TaxRepository
+}
+
+// Initializes and returns a new instance of taxService with the provided repository
+func NewTaxService(taxRepo dal.TaxRepository) TaxService {
+	return &taxService{taxRepo: taxRepo}
+}
+
+// Returns all tax rates
+func (s *taxService) ListRates() ([]models.TaxRate, error) {
+	unlock := s.taxRepo.RLock(dal.TaxRatesFile)
+	defer unlock()
+	return s.taxRepo.ReadRates()
+}
+
+// Adds a tax rate under a new ID
+func (s *taxService) CreateRate(rate models.TaxRate) error {
+	unlock := s.taxRepo.Lock(dal.TaxRatesFile)
+	defer unlock()
+	rates, err := s.taxRepo.ReadRates()
+	if err != nil {
+		return err
+	}
+	if err := checkTaxRate(&rate, rates); err != nil {
+		return err
+	}
+	if _, exists := s.taxRepo.FindRate(rate.ID); exists {
+		return errors.New("Such ID already exists")
+	}
+	return s.taxRepo.WriteRates(append(rates, rate))
+}
+
+// Replaces a tax rate; orders already placed keep the tax they were charged
+func (s *taxService) UpdateRate(id string, rate models.TaxRate) error {
+	unlock := s.taxRepo.Lock(dal.TaxRatesFile)
+	defer unlock()
+	rates, err := s.taxRepo.ReadRates()
+	if err != nil {
+		return err
+	}
+	i := slices.IndexFunc(rates, func(r models.TaxRate) bool { return r.ID == id })
+	if i < 0 {
+		return ErrTaxRateNotFound
+	}
+	rate.ID = id
+	if err := checkTaxRate(&rate, slices.Delete(slices.Clone(rates), i, i+1)); err != nil {
+		return err
+	}
+	rates[i] = rate
+	return s.taxRepo.WriteRates(rates)
+}
+
+// Deletes a tax rate; orders already placed keep the tax they were charged
+func (s *taxService) DeleteRate(id string) error {
+	unlock := s.taxRepo.Lock(dal.TaxRatesFile)
+	defer unlock()
+	rates, err := s.taxRepo.ReadRates()
+	if err != nil {
+		return err
+	}
+	i := slices.IndexFunc(rates, func(r models.TaxRate) bool { return r.ID == id })
+	if i < 0 {
+		return ErrTaxRateNotFound
+	}
+	return s.taxRepo.WriteRates(slices.Delete(rates, i, i+1))
+}
+
+// Validates a tax rate against the other rates and trims its category; no two rates may apply
+// to the same category and service type, so every line has a single most specific rate
+func checkTaxRate(rate *models.TaxRate, others []models.TaxRate) error {
+	if strings.TrimSpace(rate.ID) == "" {
+		return errors.New("Missing tax rate id")
+	}
+	if strings.TrimSpace(rate.Name) == "" {
+		return errors.New("Missing tax rate name")
+	}
+	if rate.Rate < 0 || rate.Rate > 100 {
+		return errors.New("Rate must be between 0 and 100")
+	}
+	if !isKnownServiceType(rate.ServiceType) {
+		return fmt.Errorf("Unknown service type %q, expected %s or %s", rate.ServiceType, models.ServiceDineIn, models.ServiceTakeaway)
+	}
+	rate.Category = strings.TrimSpace(rate.Category)
+	for _, other := range others {
+		if strings.EqualFold(other.Category, rate.Category) && other.ServiceType == rate.ServiceType {
+			return fmt.Errorf("Tax rate %s already applies to this category and service type", other.ID)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package service
+
+import (
+	"strings"
+
+	"hot-coffee/models"
+)
+
+// Taxes the lines of an order whose discounts are applied: each line gets the most specific
+// tax rate matching its menu category and the order's service type, and the order records
+// the tax charged at every rate. A line no rate matches, or that costs nothing, is not taxed.
+func applyTaxes(order *models.Order, rates []models.TaxRate, category func(productID string) string) {
+	order.Taxes = nil
+	for i, item := range order.Items {
+		order.Items[i].TaxRateID, order.Items[i].Tax = "", 0
+		taxable := roundMoney(item.LineTotal - item.Discount)
+		rate, exists := matchTaxRate(rates, category(item.ProductID), order.ServiceType)
+		if !exists || taxable <= 0 {
+			continue
+		}
+		tax := taxable * rate.Rate / 100
+		if rate.Inclusive {
+			// The tax is the part of the price above what it would be without the tax
+			tax = taxable - taxable/(1+rate.Rate/100)
+		}
+		order.Items[i].TaxRateID = rate.ID
+		order.Items[i].Tax = roundMoney(tax)
+		addAppliedTax(order, rate, taxable, order.Items[i].Tax)
+	}
+}
+
+// Returns the most specific tax rate matching a menu category and service type, and whether any matches;
+// among equally specific rates the first one listed wins
+func matchTaxRate(rates []models.TaxRate, category string, serviceType string) (models.TaxRate, bool) {
+	best, bestScore := models.TaxRate{}, -1
+	for _, rate := range rates {
+		score := 0
+		if rate.Category != "" {
+			if !strings.EqualFold(rate.Category, category) {
+				continue
+			}
+			score += 2
+		}
+		if rate.ServiceType != "" {
+			if rate.ServiceType != serviceType {
+				continue
+			}
+			score++
+		}
+		if score > bestScore {
+			best, bestScore = rate, score
+		}
+	}
+	return best, bestScore >= 0
+}
+
+// Adds the tax on one line to the order's total for its rate
+func addAppliedTax(order *models.Order, rate models.TaxRate, taxable float64, tax float64) {
+	for i, applied := range order.Taxes {
+		if applied.TaxRateID == rate.ID {
+			order.Taxes[i].Taxable = roundMoney(applied.Taxable + taxable)
+			order.Taxes[i].Amount = roundMoney(applied.Amount + tax)
+			return
+		}
+	}
+	order.Taxes = append(order.Taxes, models.AppliedTax{
+		TaxRateID: rate.ID,
+		Name:      rate.Name,
+		Rate:      rate.Rate,
+		Inclusive: rate.Inclusive,
+		Taxable:   taxable,
+		Amount:    tax,
+	})
+}
+
+// Reports whether a service type is one an order can have; empty means not given
+func isKnownServiceType(serviceType string) bool {
+	return serviceType == "" || serviceType == models.ServiceDineIn || serviceType == models.ServiceTakeaway
+}
@@ -0,0 +1,107 @@
+package service
+
+import (
+	"slices"
+	"testing"
+
+	"hot-coffee/models"
+)
+
+func TestMatchTaxRate(t *testing.T) {
+	standard := models.TaxRate{ID: "standard", Rate: 10}
+	food := models.TaxRate{ID: "food", Rate: 5, Category: "Food"}
+	takeaway := models.TaxRate{ID: "takeaway", Rate: 8, ServiceType: models.ServiceTakeaway}
+	foodTakeaway := models.TaxRate{ID: "food_takeaway", Rate: 2, Category: "food", ServiceType: models.ServiceTakeaway}
+	all := []models.TaxRate{standard, takeaway, food, foodTakeaway}
+	tests := []struct {
+		name        string
+		rates       []models.TaxRate
+		category    string
+		serviceType string
+		want        string // ID of the matching rate; empty when none matches.
+	}{
+		{name: "category and service type", rates: all, category: "food", serviceType: models.ServiceTakeaway, want: "food_takeaway"},
+		{name: "category beats service type", rates: all, category: "food", serviceType: models.ServiceDineIn, want: "food"},
+		{name: "category matches regardless of case", rates: all, category: "FOOD", want: "food"},
+		{name: "service type beats a rate for everything", rates: all, category: "coffee", serviceType: models.ServiceTakeaway, want: "takeaway"},
+		{name: "a rate for everything", rates: all, category: "coffee", serviceType: models.ServiceDineIn, want: "standard"},
+		{name: "service type not given", rates: all, category: "coffee", want: "standard"},
+		{name: "first of equally specific rates", rates: []models.TaxRate{{ID: "first", Category: "food"}, {ID: "second", Category: "food"}}, category: "food", want: "first"},
+		{name: "nothing matches", rates: []models.TaxRate{food, takeaway}, category: "coffee", serviceType: models.ServiceDineIn},
+		{name: "no rates", category: "food"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, exists := matchTaxRate(tt.rates, tt.category, tt.serviceType)
+			if exists != (tt.want != "") || got.ID != tt.want {
+				t.Errorf("matchTaxRate() = %q, %v, want %q", got.ID, exists, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyTaxes(t *testing.T) {
+	categories := map[string]string{"latte": "coffee", "muffin": "food", "cookie": "food"}
+	category := func(productID string) string { return categories[productID] }
+	exclusive := models.TaxRate{ID: "sales", Name: "Sales tax", Rate: 10, Category: "coffee"}
+	inclusive := models.TaxRate{ID: "vat", Name: "VAT", Rate: 20, Inclusive: true, Category: "food"}
+	line := func(productID string, lineTotal, discount float64) models.OrderItem {
+		return models.OrderItem{ProductID: productID, Quantity: 1, LineTotal: lineTotal, Discount: discount}
+	}
+	tests := []struct {
+		name      string
+		items     []models.OrderItem
+		wantTax   []float64 // Tax on each line.
+		wantTaxes []models.AppliedTax
+	}{
+		{
+			name:      "exclusive rate is added on top",
+			items:     []models.OrderItem{line("latte", 3.5, 0)},
+			wantTax:   []float64{0.35},
+			wantTaxes: []models.AppliedTax{{TaxRateID: "sales", Name: "Sales tax", Rate: 10, Taxable: 3.5, Amount: 0.35}},
+		},
+		{
+			name:      "inclusive rate is the part of the price above the untaxed price",
+			items:     []models.OrderItem{line("muffin", 12, 0)},
+			wantTax:   []float64{2},
+			wantTaxes: []models.AppliedTax{{TaxRateID: "vat", Name: "VAT", Rate: 20, Inclusive: true, Taxable: 12, Amount: 2}},
+		},
+		{
+			name:      "discounts are taken off before the tax",
+			items:     []models.OrderItem{line("latte", 7, 2)},
+			wantTax:   []float64{0.5},
+			wantTaxes: []models.AppliedTax{{TaxRateID: "sales", Name: "Sales tax", Rate: 10, Taxable: 5, Amount: 0.5}},
+		},
+		{
+			name:    "lines at one rate add up",
+			items:   []models.OrderItem{line("muffin", 6, 0), line("latte", 3.5, 0), line("cookie", 2.4, 0)},
+			wantTax: []float64{1, 0.35, 0.4},
+			wantTaxes: []models.AppliedTax{
+				{TaxRateID: "vat", Name: "VAT", Rate: 20, Inclusive: true, Taxable: 8.4, Amount: 1.4},
+				{TaxRateID: "sales", Name: "Sales tax", Rate: 10, Taxable: 3.5, Amount: 0.35},
+			},
+		},
+		{
+			name:    "free and unmatched lines are not taxed",
+			items:   []models.OrderItem{line("latte", 3.5, 3.5), line("tea", 2, 0)},
+			wantTax: []float64{0, 0},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			order := models.Order{Items: tt.items}
+			applyTaxes(&order, []models.TaxRate{exclusive, inclusive}, category)
+			for i, item := range order.Items {
+				if item.Tax != tt.wantTax[i] {
+					t.Errorf("line %d has tax %.2f, want %.2f", i, item.Tax, tt.wantTax[i])
+				}
+				if wantRate := item.Tax > 0; (item.TaxRateID != "") != wantRate {
+					t.Errorf("line %d has tax rate %q", i, item.TaxRateID)
+				}
+			}
+			if !slices.Equal(order.Taxes, tt.wantTaxes) {
+				t.Errorf("taxes are %+v, want %+v", order.Taxes, tt.wantTaxes)
+			}
+		})
+	}
+}
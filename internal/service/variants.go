@@ -0,0 +1,98 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"hot-coffee/models"
+)
+
+// Returns the variant chosen for an order item, or nil for a menu item without variants;
+// a menu item with variants requires one to be chosen
+func resolveVariant(menuItem models.MenuItem, id string) (*models.Variant, error) {
+	if len(menuItem.Variants) == 0 {
+		if id != "" {
+			return nil, fmt.Errorf("%s has no variants", menuItem.ID)
+		}
+		return nil, nil
+	}
+	names := make([]string, len(menuItem.Variants))
+	for i := range menuItem.Variants {
+		if menuItem.Variants[i].ID == id {
+			return &menuItem.Variants[i], nil
+		}
+		names[i] = menuItem.Variants[i].ID
+	}
+	if id == "" {
+		return nil, fmt.Errorf("Missing variant for %s, choose one of: %s", menuItem.ID, strings.Join(names, ", "))
+	}
+	return nil, fmt.Errorf("Unknown variant %s for %s, choose one of: %s", id, menuItem.ID, strings.Join(names, ", "))
+}
+
+// Returns the ingredients of one unit of a menu item in the given variant, by ingredient ID
+func variantRecipe(menuItem models.MenuItem, variant *models.Variant) map[string]float64 {
+	ingredients, multiplier := menuItem.Ingredients, 1.0
+	if variant != nil {
+		if len(variant.Ingredients) > 0 {
+			ingredients = variant.Ingredients
+		} else if variant.Multiplier > 0 {
+			multiplier = variant.Multiplier
+		}
+	}
+	recipe := make(map[string]float64, len(ingredients))
+	for _, ingredient := range ingredients {
+		recipe[ingredient.IngredientID] += ingredient.Quantity * multiplier
+	}
+	return recipe
+}
+
+// Returns the unit price and the ingredients of one unit of an order item, with its
+// variant and modifiers applied
+func configureItem(menuItem models.MenuItem, item models.OrderItem) (float64, map[string]float64, error) {
+	variant, err := resolveVariant(menuItem, item.Variant)
+	if err != nil {
+		return 0, nil, err
+	}
+	modifiers, err := resolveModifiers(menuItem, item.Modifiers)
+	if err != nil {
+		return 0, nil, err
+	}
+	price := menuItem.Price
+	if variant != nil {
+		price = variant.Price
+	}
+	return modifiedPrice(price, modifiers), modifiedRecipe(variantRecipe(menuItem, variant), modifiers), nil
+}
+
+// Validates the variants of a menu item; checkIngredient reports ingredients missing from the inventory
+func checkVariants(item models.MenuItem, checkIngredient func(id string) error) error {
+	ids := make(map[string]bool, len(item.Variants))
+	for _, variant := range item.Variants {
+		if strings.TrimSpace(variant.ID) == "" {
+			return errors.New("Missing variant ID")
+		}
+		if ids[variant.ID] {
+			return fmt.Errorf("Duplicate variant ID: %s", variant.ID)
+		}
+		ids[variant.ID] = true
+		if strings.TrimSpace(variant.Name) == "" {
+			return fmt.Errorf("Missing name of variant %s", variant.ID)
+		}
+		if variant.Price < 0 {
+			return fmt.Errorf("Price of variant %s cannot be negative", variant.ID)
+		}
+		if variant.Multiplier < 0 {
+			return fmt.Errorf("Multiplier of variant %s cannot be negative", variant.ID)
+		}
+		for _, ingredient := range variant.Ingredients {
+			if ingredient.Quantity <= 0 {
+				return fmt.Errorf("Ingredients quantity of variant %s cannot be 0 or negative", variant.ID)
+			}
+			if err := checkIngredient(ingredient.IngredientID); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -1,9 +1,24 @@
 package models
 
 type Total struct {
-	TotalSales float64 `json:"total_sales"`
+	TotalSales     float64        `json:"total_sales"`         // Sum of the order totals, after discounts.
+	TotalDiscounts float64        `json:"total_discounts"`     // Sum of the discounts given on those orders.
+	TotalTax       float64        `json:"total_tax"`           // Sum of the tax charged on those orders.
+	TotalTips      float64        `json:"total_tips"`          // Sum of the tips paid on those orders.
+	Payments       []PaymentTotal `json:"payments"`            // What was paid on those orders per payment method.
+	Breakdown      []SalesGroup   `json:"breakdown,omitempty"` // Set when a grouping is requested.
 }
 type Popular struct {
 	PopularSales string `json:"popular_item"`
+	Variant      string `json:"variant,omitempty"` // Set when grouping by variant.
 	Quantity     int    `json:"quantity"`
 }
+
+// SalesGroup is the sales of one product, or of one variant of a product
+type SalesGroup struct {
+	ProductID string  `json:"product_id"`
+	Variant   string  `json:"variant,omitempty"` // Set when grouping by variant.
+	Quantity  int     `json:"quantity"`
+	Sales     float64 `json:"sales"`               // Sum of the line totals less their discounts.
+	Discounts float64 `json:"discounts,omitempty"` // Sum of the discounts on those lines.
+}
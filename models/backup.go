@@ -0,0 +1,52 @@
+package models
+
+import "encoding/json"
+
+// Snapshot describes one versioned backup of the data files
+type Snapshot struct {
+	ID        string
+	CreatedAt string
+	Counts    map[string]int // Entries in each data file, by kind, such as "orders" or "menu_items".
+}
+
+// MarshalJSON writes the counts next to the ID and creation time, as in {"snapshot_id": ..., "orders": 12}
+func (s Snapshot) MarshalJSON() ([]byte, error) {
+	fields := make(map[string]any, len(s.Counts)+2)
+	for kind, count := range s.Counts {
+		fields[kind] = count
+	}
+	fields["snapshot_id"] = s.ID
+	fields["created_at"] = s.CreatedAt
+	return json.Marshal(fields)
+}
+
+// UnmarshalJSON reads a snapshot written by MarshalJSON; every field besides the ID and creation time is a count
+func (s *Snapshot) UnmarshalJSON(data []byte) error {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	*s = Snapshot{Counts: make(map[string]int, len(fields))}
+	for key, value := range fields {
+		var err error
+		switch key {
+		case "snapshot_id":
+			err = json.Unmarshal(value, &s.ID)
+		case "created_at":
+			err = json.Unmarshal(value, &s.CreatedAt)
+		default:
+			var count int
+			err = json.Unmarshal(value, &count)
+			s.Counts[key] = count
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RestoreRequest names the snapshot to restore
+type RestoreRequest struct {
+	SnapshotID string `json:"snapshot_id"`
+}
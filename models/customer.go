@@ -0,0 +1,26 @@
+package models
+
+// Customer is a registered customer that orders can be linked to by ID
+type Customer struct {
+	ID        string `json:"customer_id"`
+	Name      string `json:"name"`
+	Phone     string `json:"phone,omitempty"` // Digits only, with an optional leading "+".
+	Email     string `json:"email,omitempty"` // Lower case.
+	CreatedAt string `json:"created_at"`
+}
+
+// CustomerSummary is a customer together with the totals of their orders
+type CustomerSummary struct {
+	Customer
+	Orders     int     `json:"orders"`               // All orders linked to the customer.
+	Visits     int     `json:"visits"`               // Closed orders.
+	TotalSpent float64 `json:"total_spent"`          // Sum of the totals of the closed orders.
+	LastVisit  string  `json:"last_visit,omitempty"` // When the latest closed order was created.
+}
+
+// CustomerQuery selects customers by exact contact details or name; empty fields match everyone
+type CustomerQuery struct {
+	Name  string
+	Phone string
+	Email string
+}
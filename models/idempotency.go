@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// IdempotentResponse is the stored response to a request made with an Idempotency-Key,
+// replayed when the request is repeated with the same key
+type IdempotentResponse struct {
+	Key         string    `json:"key"`
+	Request     string    `json:"request"`      // Method and path of the request.
+	RequestHash string    `json:"request_hash"` // SHA-256 of the request body.
+	Status      int       `json:"status"`
+	ContentType string    `json:"content_type"`
+	Body        string    `json:"body"`
+	CreatedAt   time.Time `json:"created_at"`
+}
@@ -3,6 +3,7 @@ package models
 type InventoryItem struct {
 	IngredientID string  `json:"ingredient_id"`
 	Name         string  `json:"name"`
-	Quantity     float64 `json:"quantity"`
+	Quantity     float64 `json:"quantity"` // Stock on hand.
+	Reserved     float64 `json:"reserved"` // Part of the stock held for active orders.
 	Unit         string  `json:"unit"`
 }
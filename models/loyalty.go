@@ -0,0 +1,59 @@
+package models
+
+// Kinds of loyalty rules
+const (
+	LoyaltyStamps = "stamps" // One stamp for every qualifying item bought.
+	LoyaltyPoints = "points" // Points for every currency unit spent on qualifying items.
+)
+
+// Kinds of loyalty ledger entries
+const (
+	LedgerEarn    = "earn"    // Credited when an order is closed.
+	LedgerRedeem  = "redeem"  // Debited for a reward taken in an order.
+	LedgerReverse = "reverse" // Undoes the entries of a cancelled, deleted, changed or refunded order.
+	LedgerAdjust  = "adjust"  // Entered by staff, e.g. to carry over a paper stamp card.
+)
+
+// LoyaltyRule says how customers collect a balance and what a reward costs
+type LoyaltyRule struct {
+	ID   string `json:"rule_id"`
+	Name string `json:"name"`
+	Kind string `json:"kind"`
+	// Products that earn under the rule; empty means every product
+	ProductIDs []string `json:"product_ids,omitempty"`
+	// Points earned per currency unit spent, for points rules
+	PointsPerUnit float64 `json:"points_per_unit,omitempty"`
+	// Balance one reward costs
+	RewardCost int `json:"reward_cost"`
+	// Products a reward can be taken as
+	RewardProductIDs []string `json:"reward_product_ids"`
+}
+
+// LoyaltyEntry is one change to a customer's balance under a rule
+type LoyaltyEntry struct {
+	ID         string `json:"entry_id"`
+	CustomerID string `json:"customer_id"`
+	RuleID     string `json:"rule_id"`
+	OrderID    string `json:"order_id,omitempty"`
+	Kind       string `json:"kind"`
+	Points     int    `json:"points"` // Positive for credits, negative for debits.
+	Reason     string `json:"reason,omitempty"`
+	At         string `json:"at"`
+}
+
+// LoyaltyBalance is a customer's balance under one rule
+type LoyaltyBalance struct {
+	RuleID     string `json:"rule_id"`
+	Name       string `json:"name"`
+	Kind       string `json:"kind"`
+	Balance    int    `json:"balance"`
+	RewardCost int    `json:"reward_cost"`
+	Rewards    int    `json:"rewards_available"` // Rewards the balance pays for.
+}
+
+// LoyaltyAdjustment asks to credit or debit a customer's balance by hand
+type LoyaltyAdjustment struct {
+	RuleID string `json:"rule_id"`
+	Points int    `json:"points"`
+	Reason string `json:"reason"`
+}
@@ -1,13 +1,58 @@
 package models
 
+// Actions a modifier can apply to a menu item's recipe
+const (
+	ModifierAdd     = "add"     // Adds an ingredient on top of the recipe.
+	ModifierReplace = "replace" // Uses an ingredient instead of one of the recipe's.
+	ModifierRemove  = "remove"  // Leaves an ingredient of the recipe out.
+)
+
 type MenuItem struct {
-	ID          string               `json:"product_id"`
-	Name        string               `json:"name"`
-	Description string               `json:"description"`
-	Price       float64              `json:"price"`
-	Ingredients []MenuItemIngredient `json:"ingredients"`
+	ID             string               `json:"product_id"`
+	Name           string               `json:"name"`
+	Description    string               `json:"description"`
+	Price          float64              `json:"price"`
+	Category       string               `json:"category,omitempty"` // Such as "drink" or "food"; tax rates can depend on it.
+	Ingredients    []MenuItemIngredient `json:"ingredients"`
+	Variants       []Variant            `json:"variants,omitempty"`
+	ModifierGroups []ModifierGroup      `json:"modifier_groups,omitempty"`
 }
 type MenuItemIngredient struct {
 	IngredientID string  `json:"ingredient_id"`
 	Quantity     float64 `json:"quantity"`
 }
+
+// Variant is a version of a menu item, such as a size, with its own price. Its recipe is
+// either its own list of ingredients or the item's recipe scaled by the multiplier.
+type Variant struct {
+	ID          string               `json:"variant_id"`
+	Name        string               `json:"name"`
+	Price       float64              `json:"price"`
+	Multiplier  float64              `json:"multiplier,omitempty"`  // Scales the item's recipe; 0 means 1.
+	Ingredients []MenuItemIngredient `json:"ingredients,omitempty"` // Used instead of the item's recipe when set.
+}
+
+// ModifierGroup is a set of options a customer can choose from for a menu item, such as the milk
+type ModifierGroup struct {
+	ID         string     `json:"group_id"`
+	Name       string     `json:"name"`
+	Required   bool       `json:"required"`    // At least one option must be chosen.
+	MaxChoices int        `json:"max_choices"` // Options that can be chosen together; 0 means one.
+	Options    []Modifier `json:"options"`
+}
+
+// Modifier is one option of a modifier group; it adjusts the price and the recipe of the item
+type Modifier struct {
+	ID         string         `json:"modifier_id"`
+	Name       string         `json:"name"`
+	PriceDelta float64        `json:"price_delta"`
+	Changes    []RecipeChange `json:"changes"`
+}
+
+// RecipeChange is one change a modifier makes to the recipe of a single item
+type RecipeChange struct {
+	Action       string  `json:"action"` // One of ModifierAdd, ModifierReplace or ModifierRemove.
+	IngredientID string  `json:"ingredient_id"`
+	Replaces     string  `json:"replaces,omitempty"` // Recipe ingredient swapped out by a replace.
+	Quantity     float64 `json:"quantity,omitempty"` // Amount added, or used by a replace; 0 keeps the replaced amount.
+}
@@ -1,14 +1,137 @@
 package models
 
+// Layout of the timestamps stored on orders
+const TimeLayout = "2006-01-02 15:04:05"
+
+// Order statuses; the allowed moves between them are defined by the order service
+const (
+	StatusScheduled     = "scheduled"
+	StatusOpen          = "open"
+	StatusAccepted      = "accepted"
+	StatusInPreparation = "in_preparation"
+	StatusReady         = "ready"
+	StatusPickedUp      = "picked_up"
+	StatusClosed        = "closed"
+	StatusCancelled     = "cancelled"
+	StatusRefunded      = "refunded"
+)
+
 type Order struct {
-	ID           string      `json:"order_id"`
+	ID            string            `json:"order_id"`
+	CustomerName  string            `json:"customer_name"`
+	CustomerID    string            `json:"customer_id,omitempty"`  // Registered customer placing the order.
+	ServiceType   string            `json:"service_type,omitempty"` // ServiceDineIn or ServiceTakeaway, when given.
+	Items         []OrderItem       `json:"items"`
+	Status        string            `json:"status"`
+	CreatedAt     string            `json:"created_at"`
+	PickupAt      string            `json:"pickup_at,omitempty"` // Requested pickup time in TimeLayout, for pre-orders.
+	StatusHistory []StatusChange    `json:"status_history,omitempty"`
+	Subtotal      float64           `json:"subtotal"`              // Sum of the line totals.
+	PromoCodes    []string          `json:"promo_codes,omitempty"` // Promo codes given by the customer.
+	Discounts     []AppliedDiscount `json:"discounts,omitempty"`   // Promotions applied when the order was priced.
+	Discount      float64           `json:"discount,omitempty"`    // Sum of the discounts.
+	Taxes         []AppliedTax      `json:"taxes,omitempty"`       // Tax charged at each rate when the order was priced.
+	Tax           float64           `json:"tax,omitempty"`         // Sum of the taxes, included in the prices or not.
+	Total         float64           `json:"total"`                 // Amount charged for the order: the subtotal less the discount, plus the tax not included in the prices.
+	Payments      []Payment         `json:"payments,omitempty"`    // Tenders recorded against the total.
+	Paid          float64           `json:"paid,omitempty"`        // Sum of the payments' amounts, without tips.
+	Tips          float64           `json:"tips,omitempty"`        // Sum of the payments' tips.
+
+	// Ingredient amounts held in the inventory for the order while it is active, by ingredient ID
+	Reserved map[string]float64 `json:"reserved_ingredients,omitempty"`
+	// Ingredient amounts deducted from the inventory when the order was closed, by ingredient ID
+	Consumed map[string]float64 `json:"consumed_ingredients,omitempty"`
+
+	Reason    string `json:"reason,omitempty"`    // Why the order was cancelled or refunded.
+	Restocked bool   `json:"restocked,omitempty"` // The ingredients of a refunded order went back to the inventory.
+}
+
+// OrderItem is one line of an order; the prices are captured from the menu by the
+// server, so later menu changes do not alter existing orders
+type OrderItem struct {
+	ProductID string   `json:"product_id"`
+	Quantity  int      `json:"quantity"`
+	Variant   string   `json:"variant,omitempty"`   // ID of the chosen variant of the menu item.
+	Modifiers []string `json:"modifiers,omitempty"` // IDs of the chosen modifier options.
+	Reward    string   `json:"reward,omitempty"`    // ID of the loyalty rule whose reward pays for the line.
+	UnitPrice float64  `json:"unit_price"`
+	LineTotal float64  `json:"line_total"`
+	Discount  float64  `json:"discount,omitempty"`    // Part of the line total taken off by promotions.
+	TaxRateID string   `json:"tax_rate_id,omitempty"` // Tax rate the line is taxed at.
+	Tax       float64  `json:"tax,omitempty"`         // Tax on the line total less its discount.
+}
+
+// StatusChange records when an order entered a status
+type StatusChange struct {
+	Status string `json:"status"`
+	At     string `json:"at"`
+}
+
+// OrderRequest holds the fields of an order a client may set when placing it; everything else,
+// such as its status, prices and payments, is set by the server
+type OrderRequest struct {
 	CustomerName string      `json:"customer_name"`
+	CustomerID   string      `json:"customer_id,omitempty"`
+	ServiceType  string      `json:"service_type,omitempty"`
 	Items        []OrderItem `json:"items"`
-	Status       string      `json:"status"`
-	CreatedAt    string      `json:"created_at"`
+	PickupAt     string      `json:"pickup_at,omitempty"`
+	PromoCodes   []string    `json:"promo_codes,omitempty"`
 }
 
-type OrderItem struct {
-	ProductID string `json:"product_id"`
-	Quantity  int    `json:"quantity"`
+// TransitionRequest asks to move an order to a new status
+type TransitionRequest struct {
+	Status string `json:"status"`
+}
+
+// CancelRequest asks to cancel an active order or refund a closed one
+type CancelRequest struct {
+	Reason  string `json:"reason"`
+	Restock bool   `json:"restock"` // For a refund: return the ingredients to the inventory.
+}
+
+// OrderQuery selects, sorts and pages orders; empty fields select everything
+type OrderQuery struct {
+	Statuses     []string // Orders in any of these statuses.
+	CustomerName string   // Compared ignoring case and surrounding spaces.
+	CustomerID   string   // Orders linked to this customer.
+	ProductID    string   // Orders containing this product.
+	CreatedFrom  string   // Earliest creation time, as a date or in TimeLayout.
+	CreatedTo    string   // Latest creation time, as a date (the whole day) or in TimeLayout.
+	PickupFrom   string   // Earliest pickup time, like CreatedFrom; orders without one are left out.
+	PickupTo     string   // Latest pickup time, like CreatedTo; orders without one are left out.
+	SortBy       string   // One of created_at (default), pickup_at, order_id, customer_name, status or total.
+	Descending   bool
+	Offset       int
+	Limit        int // 0 means the default page size.
+}
+
+// OrderPage is one page of the orders matching a query
+type OrderPage struct {
+	Orders     []Order `json:"orders"`
+	Total      int     `json:"total"` // Number of matching orders on all pages.
+	Offset     int     `json:"offset"`
+	Limit      int     `json:"limit"`
+	NextOffset *int    `json:"next_offset"` // Offset of the next page, or null on the last page.
+}
+
+// Quote is the price of a prospective order and whether it can be made from the stock
+// not reserved by other orders
+type Quote struct {
+	Items      []OrderItem       `json:"items"`
+	Subtotal   float64           `json:"subtotal"`
+	Discounts  []AppliedDiscount `json:"discounts,omitempty"`
+	Discount   float64           `json:"discount,omitempty"`
+	Taxes      []AppliedTax      `json:"taxes,omitempty"`
+	Tax        float64           `json:"tax,omitempty"`
+	Total      float64           `json:"total"`
+	Available  bool              `json:"available"`
+	Shortfalls []Shortfall       `json:"shortfalls"`
+}
+
+// Shortfall is an ingredient an order needs more of than is available
+type Shortfall struct {
+	IngredientID string  `json:"ingredient_id"`
+	Required     float64 `json:"required"`
+	Available    float64 `json:"available"` // Stock on hand not reserved by other orders.
+	Missing      float64 `json:"missing"`
 }
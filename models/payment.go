@@ -0,0 +1,43 @@
+package models
+
+// Payment methods
+const (
+	TenderCash     = "cash"
+	TenderCard     = "card"
+	TenderGiftCard = "gift_card"
+)
+
+// Payment is one tender recorded against an order
+type Payment struct {
+	ID        string  `json:"payment_id"`
+	Method    string  `json:"method"`              // One of TenderCash, TenderCard or TenderGiftCard.
+	Amount    float64 `json:"amount"`              // Part of the order's total the tender pays.
+	Tip       float64 `json:"tip,omitempty"`       // Paid on top of the order's total.
+	Change    float64 `json:"change,omitempty"`    // Cash handed back because more was tendered than was due.
+	Reference string  `json:"reference,omitempty"` // Card transaction or gift card number.
+	Reverses  string  `json:"reverses,omitempty"`  // For a refund, the payment it pays back; its amount and tip are negative.
+	At        string  `json:"at"`                  // When the payment was recorded, in TimeLayout.
+}
+
+// PaymentRequest records one or more tenders at once, such as a bill split between card and cash
+type PaymentRequest struct {
+	Tenders []Payment `json:"tenders"`
+}
+
+// OrderPayments is how far an order has been paid
+type OrderPayments struct {
+	OrderID  string    `json:"order_id"`
+	Total    float64   `json:"total"`
+	Paid     float64   `json:"paid"`
+	Tips     float64   `json:"tips"`
+	Balance  float64   `json:"balance"` // Part of the total still to be paid.
+	Payments []Payment `json:"payments"`
+}
+
+// PaymentTotal is what was paid with one payment method
+type PaymentTotal struct {
+	Method   string  `json:"method"`
+	Payments int     `json:"payments"`
+	Amount   float64 `json:"amount"`
+	Tips     float64 `json:"tips"`
+}
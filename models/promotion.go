@@ -0,0 +1,52 @@
+package models
+
+// Kinds of promotions
+const (
+	PromoPercentOff = "percent_off" // A percentage off the qualifying items.
+	PromoFixedOff   = "fixed_off"   // An amount off each qualifying item, or off the order when no products are named.
+	PromoFixedPrice = "fixed_price" // The qualifying items sell at a set price, e.g. during happy hour.
+	PromoBuyXGetY   = "buy_x_get_y" // For every BuyQuantity qualifying items bought, GetQuantity more are free.
+)
+
+// Layouts of the time of day and date limits of a promotion
+const (
+	PromoTimeLayout = "15:04"
+	PromoDateLayout = "2006-01-02"
+)
+
+// Promotion is a discount applied automatically when an order is priced, or when the order
+// carries its promo code
+type Promotion struct {
+	ID   string `json:"promotion_id"`
+	Name string `json:"name"`
+	Type string `json:"type"`
+	// Products the promotion applies to; empty means every product
+	ProductIDs []string `json:"product_ids,omitempty"`
+	// The order must contain one of these products; items discounted one by one are
+	// limited to one per required item bought, which makes combo deals
+	RequiresProductIDs []string `json:"requires_product_ids,omitempty"`
+
+	Percent     float64 `json:"percent,omitempty"`      // For percent_off.
+	Amount      float64 `json:"amount,omitempty"`       // For fixed_off.
+	Price       float64 `json:"price,omitempty"`        // For fixed_price.
+	BuyQuantity int     `json:"buy_quantity,omitempty"` // For buy_x_get_y.
+	GetQuantity int     `json:"get_quantity,omitempty"` // For buy_x_get_y.
+
+	// Promo code the order must carry; empty for promotions applied automatically
+	Code string `json:"code,omitempty"`
+	// Days of the week ("mon" to "sun") and local time of day, in PromoTimeLayout, the
+	// promotion runs; empty means always. An end at or before the start runs past midnight.
+	Days      []string `json:"days,omitempty"`
+	StartTime string   `json:"start_time,omitempty"`
+	EndTime   string   `json:"end_time,omitempty"`
+	// First and last day, in PromoDateLayout, the promotion runs; empty means unbounded
+	ValidFrom string `json:"valid_from,omitempty"`
+	ValidTo   string `json:"valid_to,omitempty"`
+}
+
+// AppliedDiscount records how much a promotion took off an order
+type AppliedDiscount struct {
+	PromotionID string  `json:"promotion_id"`
+	Name        string  `json:"name"`
+	Amount      float64 `json:"amount"`
+}
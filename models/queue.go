@@ -0,0 +1,20 @@
+package models
+
+// Kinds of order events
+const (
+	EventOrderCreated   = "order_created"
+	EventOrderUpdated   = "order_updated"
+	EventOrderPaid      = "order_paid"
+	EventStatusChanged  = "order_status_changed"
+	EventOrderClosed    = "order_closed"
+	EventOrderCancelled = "order_cancelled"
+	EventOrderRefunded  = "order_refunded"
+	EventOrderDeleted   = "order_deleted"
+)
+
+// OrderEvent reports a change to an order
+type OrderEvent struct {
+	Type  string `json:"type"`
+	At    string `json:"at"`    // When the change was saved, in TimeLayout.
+	Order Order  `json:"order"` // The order after the change; for a deletion, before it.
+}
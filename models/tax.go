@@ -0,0 +1,51 @@
+package models
+
+// Service types of an order; tax rates can differ between them
+const (
+	ServiceDineIn   = "dine_in"
+	ServiceTakeaway = "takeaway"
+)
+
+// TaxRate is a tax charged on the order lines it matches. A line is taxed at the most specific
+// rate matching it: one naming both the item's menu category and the order's service type beats
+// one naming only the category, which beats one naming only the service type, which beats one naming neither.
+type TaxRate struct {
+	ID          string  `json:"tax_rate_id"`
+	Name        string  `json:"name"`
+	Rate        float64 `json:"rate"`                   // Percentage, e.g. 8.5.
+	Inclusive   bool    `json:"inclusive"`              // Menu prices already include the tax; otherwise it is added to the total.
+	Category    string  `json:"category,omitempty"`     // Menu category the rate applies to; empty means every category.
+	ServiceType string  `json:"service_type,omitempty"` // Service type the rate applies to; empty means both.
+}
+
+// AppliedTax is the tax charged on an order at one rate
+type AppliedTax struct {
+	TaxRateID string  `json:"tax_rate_id"`
+	Name      string  `json:"name"`
+	Rate      float64 `json:"rate"`
+	Inclusive bool    `json:"inclusive"`
+	Taxable   float64 `json:"taxable"` // Line totals less their discounts the tax was computed on.
+	Amount    float64 `json:"amount"`
+}
+
+// TaxReport is the tax charged on the orders of a period, per tax rate
+type TaxReport struct {
+	From       string       `json:"from,omitempty"`
+	To         string       `json:"to,omitempty"`
+	Rates      []TaxSummary `json:"rates"`
+	NetSales   float64      `json:"net_sales"` // Sales excluding tax.
+	Tax        float64      `json:"tax"`
+	GrossSales float64      `json:"gross_sales"` // Sales including tax.
+}
+
+// TaxSummary is the sales taxed at one rate; a rate whose percentage or pricing changed is
+// reported once for each version, and untaxed sales are reported without a rate ID
+type TaxSummary struct {
+	TaxRateID string  `json:"tax_rate_id"`
+	Name      string  `json:"name"`
+	Rate      float64 `json:"rate"`
+	Inclusive bool    `json:"inclusive"`
+	Net       float64 `json:"net"`
+	Tax       float64 `json:"tax"`
+	Gross     float64 `json:"gross"`
+}